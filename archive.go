@@ -0,0 +1,507 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/micro-agent/mcp-files-server/workspacefs"
+)
+
+// archiveChunkSize bounds how much of an archive entry extract_archive
+// buffers at once while streaming it to disk.
+const archiveChunkSize = 32 * 1024
+
+// stringArrayArg reads an optional array-of-strings argument, returning nil
+// if the key is absent so callers can treat "not given" and "empty" alike.
+func stringArrayArg(args map[string]interface{}, key string) ([]string, error) {
+	raw, exists := args[key]
+	if !exists || raw == nil {
+		return nil, nil
+	}
+	items, ok := raw.([]interface{})
+	if !ok {
+		return nil, fmt.Errorf("parameter '%s' must be an array of strings", key)
+	}
+	values := make([]string, 0, len(items))
+	for _, item := range items {
+		s, ok := item.(string)
+		if !ok {
+			return nil, fmt.Errorf("parameter '%s' must be an array of strings", key)
+		}
+		values = append(values, s)
+	}
+	return values, nil
+}
+
+// matchesAnyGlob reports whether relPath (or its base name) matches any of
+// the given shell globs.
+func matchesAnyGlob(globs []string, relPath string) bool {
+	slashPath := filepath.ToSlash(relPath)
+	for _, g := range globs {
+		if ok, _ := path.Match(g, slashPath); ok {
+			return true
+		}
+		if ok, _ := path.Match(g, path.Base(slashPath)); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func archiveDirectoryHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+
+	directoryPathArg, exists := args["directory_path"]
+	if !exists || directoryPathArg == nil {
+		return nil, fmt.Errorf("missing required parameter 'directory_path'")
+	}
+	directoryPath, ok := directoryPathArg.(string)
+	if !ok {
+		return nil, fmt.Errorf("parameter 'directory_path' must be a string")
+	}
+
+	outputPathArg, exists := args["output_path"]
+	if !exists || outputPathArg == nil {
+		return nil, fmt.Errorf("missing required parameter 'output_path'")
+	}
+	outputPath, ok := outputPathArg.(string)
+	if !ok {
+		return nil, fmt.Errorf("parameter 'output_path' must be a string")
+	}
+
+	format := "zip"
+	if formatArg, exists := args["format"]; exists && formatArg != nil {
+		format, ok = formatArg.(string)
+		if !ok {
+			return nil, fmt.Errorf("parameter 'format' must be a string")
+		}
+	}
+	if format != "zip" && format != "tar" && format != "tar.gz" {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid format %q: must be zip, tar, or tar.gz", format)), nil
+	}
+
+	excludeGlobs, err := stringArrayArg(args, "exclude_globs")
+	if err != nil {
+		return nil, err
+	}
+
+	srcInfo, srcPath, err := ws.Stat(directoryPath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid directory path: %v", err)), nil
+	}
+	if !srcInfo.IsDir() {
+		return mcp.NewToolResultError(fmt.Sprintf("Path is not a directory: %s", srcPath)), nil
+	}
+	srcRel, err := filepath.Rel(ws.Root(), srcPath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error resolving path: %v", err)), nil
+	}
+
+	if _, err := ws.Mkdir(filepath.Dir(outputPath), 0755, true); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error creating directory: %v", err)), nil
+	}
+	outFile, outAbsPath, err := ws.OpenArchive(outputPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid output path: %v", err)), nil
+	}
+	defer outFile.Close()
+
+	var entryCount int
+	var uncompressedSize int64
+
+	writeEntries := func(add func(relPath string, info fs.FileInfo, linkTarget string) error) error {
+		return filepath.WalkDir(srcPath, func(p string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if p == srcPath {
+				return nil
+			}
+
+			relPath, err := filepath.Rel(srcPath, p)
+			if err != nil {
+				return err
+			}
+
+			if d.IsDir() && matchesAnyGlob(excludeGlobs, relPath) {
+				return filepath.SkipDir
+			}
+			if matchesAnyGlob(excludeGlobs, relPath) {
+				return nil
+			}
+
+			linkTarget := ""
+			if d.Type()&os.ModeSymlink != 0 {
+				target, err := os.Readlink(p)
+				if err != nil {
+					return err
+				}
+				resolved := target
+				if !filepath.IsAbs(resolved) {
+					resolved = filepath.Join(filepath.Dir(p), target)
+				}
+				if !workspacefs.WithinRoot(ws.Root(), filepath.Clean(resolved)) {
+					return fmt.Errorf("refusing to archive symlink %s: target %s escapes the workspace", relPath, target)
+				}
+				linkTarget = target
+			}
+
+			info, err := d.Info()
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && linkTarget == "" {
+				uncompressedSize += info.Size()
+			}
+			entryCount++
+
+			return add(relPath, info, linkTarget)
+		})
+	}
+
+	switch format {
+	case "zip":
+		zw := zip.NewWriter(outFile)
+		err = writeEntries(func(relPath string, info fs.FileInfo, linkTarget string) error {
+			return addZipEntry(zw, filepath.Join(srcRel, relPath), relPath, info, linkTarget)
+		})
+		if closeErr := zw.Close(); err == nil {
+			err = closeErr
+		}
+	case "tar":
+		tw := tar.NewWriter(outFile)
+		err = writeEntries(func(relPath string, info fs.FileInfo, linkTarget string) error {
+			return addTarEntry(tw, filepath.Join(srcRel, relPath), relPath, info, linkTarget)
+		})
+		if closeErr := tw.Close(); err == nil {
+			err = closeErr
+		}
+	case "tar.gz":
+		gw := gzip.NewWriter(outFile)
+		tw := tar.NewWriter(gw)
+		err = writeEntries(func(relPath string, info fs.FileInfo, linkTarget string) error {
+			return addTarEntry(tw, filepath.Join(srcRel, relPath), relPath, info, linkTarget)
+		})
+		if closeErr := tw.Close(); err == nil {
+			err = closeErr
+		}
+		if closeErr := gw.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error creating archive: %v", err)), nil
+	}
+
+	archiveInfo, err := outFile.Stat()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error stat'ing archive: %v", err)), nil
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"entry_count":       entryCount,
+		"uncompressed_size": uncompressedSize,
+		"compressed_size":   archiveInfo.Size(),
+		"format":            format,
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error encoding result: %v", err)), nil
+	}
+
+	log.Printf("Successfully archived directory: %s -> %s (%d entries, format=%s)", srcPath, outAbsPath, entryCount, format)
+	return mcp.NewToolResultText(string(payload)), nil
+}
+
+// addZipEntry adds one archive entry for relPath (used for the zip entry
+// name), reading its content through ws.Open(wsRelPath) rather than a
+// path-based os.Open, so the file read is guaranteed to be the same one the
+// walk just reported instead of whatever that path resolves to by the time
+// this runs.
+func addZipEntry(zw *zip.Writer, wsRelPath, relPath string, info fs.FileInfo, linkTarget string) error {
+	header, err := zip.FileInfoHeader(info)
+	if err != nil {
+		return err
+	}
+	header.Name = filepath.ToSlash(relPath)
+	header.Modified = info.ModTime()
+
+	if info.IsDir() {
+		header.Name += "/"
+		header.Method = zip.Store
+		_, err := zw.CreateHeader(header)
+		return err
+	}
+
+	header.Method = zip.Deflate
+	w, err := zw.CreateHeader(header)
+	if err != nil {
+		return err
+	}
+
+	if linkTarget != "" {
+		_, err := w.Write([]byte(linkTarget))
+		return err
+	}
+
+	f, _, err := ws.Open(wsRelPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.CopyBuffer(w, f, make([]byte, archiveChunkSize))
+	return err
+}
+
+// addTarEntry is addZipEntry's tar equivalent; see its comment for why the
+// read goes through ws.Open(wsRelPath) instead of a path-based os.Open.
+func addTarEntry(tw *tar.Writer, wsRelPath, relPath string, info fs.FileInfo, linkTarget string) error {
+	var header *tar.Header
+	var err error
+	if linkTarget != "" {
+		header, err = tar.FileInfoHeader(info, linkTarget)
+	} else {
+		header, err = tar.FileInfoHeader(info, "")
+	}
+	if err != nil {
+		return err
+	}
+	header.Name = filepath.ToSlash(relPath)
+	if info.IsDir() {
+		header.Name += "/"
+	}
+
+	if err := tw.WriteHeader(header); err != nil {
+		return err
+	}
+
+	if info.IsDir() || linkTarget != "" {
+		return nil
+	}
+
+	f, _, err := ws.Open(wsRelPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	_, err = io.CopyBuffer(tw, f, make([]byte, archiveChunkSize))
+	return err
+}
+
+func extractArchiveHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+
+	archivePathArg, exists := args["archive_path"]
+	if !exists || archivePathArg == nil {
+		return nil, fmt.Errorf("missing required parameter 'archive_path'")
+	}
+	archivePath, ok := archivePathArg.(string)
+	if !ok {
+		return nil, fmt.Errorf("parameter 'archive_path' must be a string")
+	}
+
+	destinationPathArg, exists := args["destination_path"]
+	if !exists || destinationPathArg == nil {
+		return nil, fmt.Errorf("missing required parameter 'destination_path'")
+	}
+	destinationPath, ok := destinationPathArg.(string)
+	if !ok {
+		return nil, fmt.Errorf("parameter 'destination_path' must be a string")
+	}
+
+	format := "auto"
+	if formatArg, exists := args["format"]; exists && formatArg != nil {
+		format, ok = formatArg.(string)
+		if !ok {
+			return nil, fmt.Errorf("parameter 'format' must be a string")
+		}
+	}
+
+	stripComponents := 0
+	if stripArg, exists := args["strip_components"]; exists && stripArg != nil {
+		stripFloat, ok := stripArg.(float64)
+		if !ok {
+			return nil, fmt.Errorf("parameter 'strip_components' must be a number")
+		}
+		stripComponents = int(stripFloat)
+	}
+	if stripComponents < 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid strip_components %d: must not be negative", stripComponents)), nil
+	}
+
+	if format == "auto" {
+		switch {
+		case strings.HasSuffix(archivePath, ".tar.gz"), strings.HasSuffix(archivePath, ".tgz"):
+			format = "tar.gz"
+		case strings.HasSuffix(archivePath, ".tar"):
+			format = "tar"
+		case strings.HasSuffix(archivePath, ".zip"):
+			format = "zip"
+		default:
+			return mcp.NewToolResultError(fmt.Sprintf("Cannot auto-detect archive format for %q; pass format explicitly", archivePath)), nil
+		}
+	}
+	if format != "zip" && format != "tar" && format != "tar.gz" {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid format %q: must be auto, zip, tar, or tar.gz", format)), nil
+	}
+
+	archiveFile, _, err := ws.OpenArchive(archivePath, os.O_RDONLY, 0)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid archive path: %v", err)), nil
+	}
+	defer archiveFile.Close()
+
+	destAbs, err := ws.ExtractArchive(destinationPath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error creating destination directory: %v", err)), nil
+	}
+
+	var entryCount int
+	var bytesWritten int64
+
+	extract := func(name string, mode os.FileMode, isDir bool, linkTarget string, r io.Reader) error {
+		cleanName := path.Clean("/" + filepath.ToSlash(name))
+		cleanName = strings.TrimPrefix(cleanName, "/")
+		if cleanName == "" || cleanName == "." {
+			return nil
+		}
+		parts := strings.Split(cleanName, "/")
+		if stripComponents >= len(parts) {
+			return nil
+		}
+		parts = parts[stripComponents:]
+		if len(parts) == 0 {
+			return nil
+		}
+		cleanName = strings.Join(parts, "/")
+		if strings.HasPrefix(cleanName, "../") || cleanName == ".." {
+			return fmt.Errorf("archive entry %q escapes destination", name)
+		}
+
+		target := filepath.Join(destAbs, filepath.FromSlash(cleanName))
+		if !workspacefs.WithinRoot(destAbs, target) {
+			return fmt.Errorf("archive entry %q escapes destination", name)
+		}
+		targetRel, err := filepath.Rel(ws.Root(), target)
+		if err != nil {
+			return err
+		}
+
+		if isDir {
+			_, err := ws.Mkdir(targetRel, 0755, true)
+			return err
+		}
+
+		if linkTarget != "" {
+			// Symlinks inside archives are not followed or recreated; skip
+			// them rather than risk pointing outside the workspace.
+			return nil
+		}
+
+		if _, err := ws.Mkdir(filepath.Dir(targetRel), 0755, true); err != nil {
+			return err
+		}
+		f, _, err := ws.Create(targetRel, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		n, err := io.CopyBuffer(f, r, make([]byte, archiveChunkSize))
+		bytesWritten += n
+		entryCount++
+		return err
+	}
+
+	switch format {
+	case "tar", "tar.gz":
+		var tarReader *tar.Reader
+		if format == "tar.gz" {
+			gr, err := gzip.NewReader(archiveFile)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("Error reading gzip archive: %v", err)), nil
+			}
+			defer gr.Close()
+			tarReader = tar.NewReader(gr)
+		} else {
+			tarReader = tar.NewReader(archiveFile)
+		}
+
+		for {
+			header, err := tarReader.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("Error reading tar archive: %v", err)), nil
+			}
+			linkTarget := ""
+			if header.Typeflag == tar.TypeSymlink {
+				linkTarget = header.Linkname
+			}
+			if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeDir && linkTarget == "" {
+				continue
+			}
+			if err := extract(header.Name, os.FileMode(header.Mode), header.Typeflag == tar.TypeDir, linkTarget, tarReader); err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("Error extracting archive: %v", err)), nil
+			}
+		}
+	case "zip":
+		info, err := archiveFile.Stat()
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Error stat'ing archive: %v", err)), nil
+		}
+		zr, err := zip.NewReader(archiveFile, info.Size())
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Error reading zip archive: %v", err)), nil
+		}
+		for _, zf := range zr.File {
+			isDir := zf.FileInfo().IsDir()
+			isSymlink := zf.Mode()&os.ModeSymlink != 0
+			err := func() error {
+				var r io.Reader
+				if !isDir && !isSymlink {
+					rc, err := zf.Open()
+					if err != nil {
+						return err
+					}
+					defer rc.Close()
+					r = rc
+				}
+				linkTarget := ""
+				if isSymlink {
+					linkTarget = "symlink"
+				}
+				return extract(zf.Name, zf.Mode(), isDir, linkTarget, r)
+			}()
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("Error extracting archive: %v", err)), nil
+			}
+		}
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"entry_count":   entryCount,
+		"bytes_written": bytesWritten,
+		"format":        format,
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error encoding result: %v", err)), nil
+	}
+
+	log.Printf("Successfully extracted archive: %s -> %s (%d entries)", archivePath, destAbs, entryCount)
+	return mcp.NewToolResultText(string(payload)), nil
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/micro-agent/mcp-files-server/workspacefs"
+)
+
+// buildZip writes a zip archive to path containing one entry per
+// (name, content) pair, with no directory entries.
+func buildZip(t *testing.T, path string, entries map[string]string) {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range entries {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatalf("zip.Create(%q): %v", name, err)
+		}
+		if _, err := w.Write([]byte(content)); err != nil {
+			t.Fatalf("zip write %q: %v", name, err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("zip.Close: %v", err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0644); err != nil {
+		t.Fatalf("WriteFile(%q): %v", path, err)
+	}
+}
+
+func callExtractArchive(t *testing.T, args map[string]interface{}) *mcp.CallToolResult {
+	t.Helper()
+	req := mcp.CallToolRequest{Params: mcp.CallToolParams{Name: "extract_archive", Arguments: args}}
+	result, err := extractArchiveHandler(context.Background(), req)
+	if err != nil {
+		t.Fatalf("extractArchiveHandler returned unexpected Go error: %v", err)
+	}
+	return result
+}
+
+func TestExtractArchiveHandler_NeutralizesPathTraversal(t *testing.T) {
+	root := t.TempDir()
+	fsys, err := workspacefs.NewLocal(root)
+	if err != nil {
+		t.Fatalf("NewLocal(%q): %v", root, err)
+	}
+	oldWS := ws
+	ws = fsys
+	defer func() { ws = oldWS }()
+
+	archivePath := filepath.Join(root, "evil.zip")
+	// extract cleans each entry name as if rooted ("/../../escape.txt" ->
+	// "/escape.txt") before ever joining it to the destination, so a
+	// traversal attempt collapses into a plain in-destination name instead
+	// of escaping it.
+	buildZip(t, archivePath, map[string]string{
+		"../../escape.txt": "payload",
+	})
+
+	result := callExtractArchive(t, map[string]interface{}{
+		"archive_path":     "evil.zip",
+		"destination_path": "out",
+	})
+	if result.IsError {
+		t.Fatalf("expected extract_archive to neutralize the traversal and succeed, got error: %+v", result)
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "out", "escape.txt")); err != nil {
+		t.Fatalf("expected escape.txt inside the destination: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "escape.txt")); err == nil {
+		t.Fatal("entry landed in the workspace root instead of the destination")
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(root), "escape.txt")); err == nil {
+		t.Fatal("entry escaped the workspace entirely")
+	}
+}
+
+func TestExtractArchiveHandler_StripComponentsAfterTraversalStaysInDestination(t *testing.T) {
+	root := t.TempDir()
+	fsys, err := workspacefs.NewLocal(root)
+	if err != nil {
+		t.Fatalf("NewLocal(%q): %v", root, err)
+	}
+	oldWS := ws
+	ws = fsys
+	defer func() { ws = oldWS }()
+
+	archivePath := filepath.Join(root, "evil.zip")
+	// Cleaning treats the name as rooted ("/a/../../b/escape.txt" ->
+	// "/b/escape.txt") before strip_components ever counts path segments,
+	// so a traversal attempt just collapses to a shorter in-root name
+	// instead of surviving to escape the destination.
+	buildZip(t, archivePath, map[string]string{
+		"a/../../b/escape.txt": "payload",
+	})
+
+	result := callExtractArchive(t, map[string]interface{}{
+		"archive_path":     "evil.zip",
+		"destination_path": "out",
+		"strip_components": float64(1),
+	})
+	if result.IsError {
+		t.Fatalf("expected extract_archive to succeed once the entry is cleaned into destination, got error: %+v", result)
+	}
+
+	if _, err := os.Stat(filepath.Join(root, "out", "escape.txt")); err != nil {
+		t.Fatalf("expected escape.txt inside destination: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "escape.txt")); err == nil {
+		t.Fatal("entry landed in the workspace root instead of the destination")
+	}
+}
+
+func TestExtractArchiveHandler_RejectsNegativeStripComponents(t *testing.T) {
+	root := t.TempDir()
+	fsys, err := workspacefs.NewLocal(root)
+	if err != nil {
+		t.Fatalf("NewLocal(%q): %v", root, err)
+	}
+	oldWS := ws
+	ws = fsys
+	defer func() { ws = oldWS }()
+
+	archivePath := filepath.Join(root, "plain.zip")
+	buildZip(t, archivePath, map[string]string{"file.txt": "hi"})
+
+	result := callExtractArchive(t, map[string]interface{}{
+		"archive_path":     "plain.zip",
+		"destination_path": "out",
+		"strip_components": float64(-1),
+	})
+	if !result.IsError {
+		t.Fatalf("expected extract_archive to reject a negative strip_components, got success: %+v", result)
+	}
+}
@@ -0,0 +1,375 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"golang.org/x/sys/unix"
+)
+
+// copyBufferPool reuses io.Copy buffers across copy_file/copy_directory
+// calls instead of allocating one per file copied.
+var copyBufferPool = sync.Pool{
+	New: func() interface{} {
+		buf := make([]byte, 256*1024)
+		return &buf
+	},
+}
+
+func copyFileHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+
+	sourcePathArg, exists := args["source_path"]
+	if !exists || sourcePathArg == nil {
+		return nil, fmt.Errorf("missing required parameter 'source_path'")
+	}
+	sourcePath, ok := sourcePathArg.(string)
+	if !ok {
+		return nil, fmt.Errorf("parameter 'source_path' must be a string")
+	}
+
+	destinationPathArg, exists := args["destination_path"]
+	if !exists || destinationPathArg == nil {
+		return nil, fmt.Errorf("missing required parameter 'destination_path'")
+	}
+	destinationPath, ok := destinationPathArg.(string)
+	if !ok {
+		return nil, fmt.Errorf("parameter 'destination_path' must be a string")
+	}
+
+	overwrite, err := boolArg(args, "overwrite", false)
+	if err != nil {
+		return nil, err
+	}
+	preserveMode, err := boolArg(args, "preserve_mode", true)
+	if err != nil {
+		return nil, err
+	}
+	followSymlinks, err := boolArg(args, "follow_symlinks", false)
+	if err != nil {
+		return nil, err
+	}
+
+	written, srcAbs, dstAbs, err := copyFile(sourcePath, destinationPath, overwrite, preserveMode, followSymlinks)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return mcp.NewToolResultError(fmt.Sprintf("File not found: %s", sourcePath)), nil
+		}
+		if errors.Is(err, fs.ErrExist) {
+			return mcp.NewToolResultError(fmt.Sprintf("Destination already exists: %s", destinationPath)), nil
+		}
+		return mcp.NewToolResultError(fmt.Sprintf("Error copying file: %v", err)), nil
+	}
+
+	log.Printf("Successfully copied file: %s -> %s (%d bytes)", srcAbs, dstAbs, written)
+	return mcp.NewToolResultText(fmt.Sprintf("Successfully copied %d bytes from %s to %s", written, srcAbs, dstAbs)), nil
+}
+
+// copyFile copies the single file at sourcePath to destinationPath, both
+// workspace-relative, returning the number of bytes written and each
+// path's resolved absolute form. When overwrite is false, it fails if
+// destinationPath already exists instead of truncating it.
+func copyFile(sourcePath, destinationPath string, overwrite, preserveMode, followSymlinks bool) (written int64, srcAbs, dstAbs string, err error) {
+	open := ws.Open
+	if followSymlinks {
+		open = ws.OpenFollow
+	}
+
+	src, srcAbs, err := open(sourcePath)
+	if err != nil {
+		return 0, "", "", err
+	}
+	defer src.Close()
+
+	srcInfo, err := src.Stat()
+	if err != nil {
+		return 0, "", "", err
+	}
+	if srcInfo.IsDir() {
+		return 0, "", "", fmt.Errorf("source is a directory, not a file: %s", srcAbs)
+	}
+
+	if !overwrite {
+		if _, _, err := ws.Lstat(destinationPath); err == nil {
+			return 0, "", "", fmt.Errorf("%w: %s", fs.ErrExist, destinationPath)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return 0, "", "", err
+		}
+	}
+
+	if _, err := ws.Mkdir(filepath.Dir(destinationPath), 0755, true); err != nil {
+		return 0, "", "", err
+	}
+
+	mode := os.FileMode(0644)
+	if preserveMode {
+		mode = srcInfo.Mode().Perm()
+	}
+
+	dst, dstAbs, err := ws.Create(destinationPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, mode)
+	if err != nil {
+		return 0, "", "", err
+	}
+	defer dst.Close()
+
+	bufPtr := copyBufferPool.Get().(*[]byte)
+	defer copyBufferPool.Put(bufPtr)
+
+	written, err = io.CopyBuffer(dst, src, *bufPtr)
+	if err != nil {
+		return 0, "", "", err
+	}
+	return written, srcAbs, dstAbs, nil
+}
+
+func moveFileHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+
+	sourcePathArg, exists := args["source_path"]
+	if !exists || sourcePathArg == nil {
+		return nil, fmt.Errorf("missing required parameter 'source_path'")
+	}
+	sourcePath, ok := sourcePathArg.(string)
+	if !ok {
+		return nil, fmt.Errorf("parameter 'source_path' must be a string")
+	}
+
+	destinationPathArg, exists := args["destination_path"]
+	if !exists || destinationPathArg == nil {
+		return nil, fmt.Errorf("missing required parameter 'destination_path'")
+	}
+	destinationPath, ok := destinationPathArg.(string)
+	if !ok {
+		return nil, fmt.Errorf("parameter 'destination_path' must be a string")
+	}
+
+	overwrite, err := boolArg(args, "overwrite", false)
+	if err != nil {
+		return nil, err
+	}
+
+	srcAbs, dstAbs, err := moveFile(sourcePath, destinationPath, overwrite)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return mcp.NewToolResultError(fmt.Sprintf("File not found: %s", sourcePath)), nil
+		}
+		if errors.Is(err, fs.ErrExist) {
+			return mcp.NewToolResultError(fmt.Sprintf("Destination already exists: %s", destinationPath)), nil
+		}
+		return mcp.NewToolResultError(fmt.Sprintf("Error moving file: %v", err)), nil
+	}
+
+	log.Printf("Successfully moved file: %s -> %s", srcAbs, dstAbs)
+	return mcp.NewToolResultText(fmt.Sprintf("Successfully moved %s to %s", srcAbs, dstAbs)), nil
+}
+
+// moveFile moves sourcePath to destinationPath, preferring the atomic
+// Rename and falling back to copy+delete when they land on different
+// filesystems (EXDEV), which Rename can't cross.
+func moveFile(sourcePath, destinationPath string, overwrite bool) (srcAbs, dstAbs string, err error) {
+	if !overwrite {
+		if _, _, err := ws.Lstat(destinationPath); err == nil {
+			return "", "", fmt.Errorf("%w: %s", fs.ErrExist, destinationPath)
+		} else if !errors.Is(err, fs.ErrNotExist) {
+			return "", "", err
+		}
+	}
+
+	oldAbs, newAbs, err := ws.Rename(sourcePath, destinationPath)
+	if err == nil {
+		return oldAbs, newAbs, nil
+	}
+	if !errors.Is(err, unix.EXDEV) {
+		return "", "", err
+	}
+
+	// Source and destination are on different filesystems, so Rename can't
+	// move them atomically; fall back to copying the content and then
+	// removing the source.
+	_, srcAbs, dstAbs, err = copyFile(sourcePath, destinationPath, true, true, false)
+	if err != nil {
+		return "", "", err
+	}
+	if _, err := ws.Remove(sourcePath); err != nil {
+		return "", "", err
+	}
+	return srcAbs, dstAbs, nil
+}
+
+func renameHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+
+	oldPathArg, exists := args["old_path"]
+	if !exists || oldPathArg == nil {
+		return nil, fmt.Errorf("missing required parameter 'old_path'")
+	}
+	oldPath, ok := oldPathArg.(string)
+	if !ok {
+		return nil, fmt.Errorf("parameter 'old_path' must be a string")
+	}
+
+	newPathArg, exists := args["new_path"]
+	if !exists || newPathArg == nil {
+		return nil, fmt.Errorf("missing required parameter 'new_path'")
+	}
+	newPath, ok := newPathArg.(string)
+	if !ok {
+		return nil, fmt.Errorf("parameter 'new_path' must be a string")
+	}
+
+	oldAbs, newAbs, err := ws.Rename(oldPath, newPath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return mcp.NewToolResultError(fmt.Sprintf("Path not found: %s", oldPath)), nil
+		}
+		return mcp.NewToolResultError(fmt.Sprintf("Error renaming path: %v", err)), nil
+	}
+
+	log.Printf("Successfully renamed: %s -> %s", oldAbs, newAbs)
+	return mcp.NewToolResultText(fmt.Sprintf("Successfully renamed %s to %s", oldAbs, newAbs)), nil
+}
+
+func copyDirectoryHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+
+	sourcePathArg, exists := args["source_path"]
+	if !exists || sourcePathArg == nil {
+		return nil, fmt.Errorf("missing required parameter 'source_path'")
+	}
+	sourcePath, ok := sourcePathArg.(string)
+	if !ok {
+		return nil, fmt.Errorf("parameter 'source_path' must be a string")
+	}
+
+	destinationPathArg, exists := args["destination_path"]
+	if !exists || destinationPathArg == nil {
+		return nil, fmt.Errorf("missing required parameter 'destination_path'")
+	}
+	destinationPath, ok := destinationPathArg.(string)
+	if !ok {
+		return nil, fmt.Errorf("parameter 'destination_path' must be a string")
+	}
+
+	overwrite, err := boolArg(args, "overwrite", false)
+	if err != nil {
+		return nil, err
+	}
+	preserveMode, err := boolArg(args, "preserve_mode", true)
+	if err != nil {
+		return nil, err
+	}
+	followSymlinks, err := boolArg(args, "follow_symlinks", false)
+	if err != nil {
+		return nil, err
+	}
+	excludeGlobs, err := stringArrayArg(args, "exclude_globs")
+	if err != nil {
+		return nil, err
+	}
+
+	srcInfo, srcAbs, err := ws.Stat(sourcePath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid source path: %v", err)), nil
+	}
+	if !srcInfo.IsDir() {
+		return mcp.NewToolResultError(fmt.Sprintf("Path is not a directory: %s", srcAbs)), nil
+	}
+
+	destAbs, err := ws.Mkdir(destinationPath, 0755, true)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error creating destination directory: %v", err)), nil
+	}
+
+	var dirCount, fileCount int
+	var bytesCopied int64
+
+	err = ws.Walk(sourcePath, followSymlinks, func(relPath string, d fs.DirEntry) error {
+		if matchesAnyGlob(excludeGlobs, relPath) {
+			if d.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+
+		srcRel := filepath.Join(sourcePath, relPath)
+		destRel := filepath.Join(destinationPath, relPath)
+
+		isDir := d.IsDir()
+		if d.Type()&os.ModeSymlink != 0 {
+			// Walk only reports a symlink at all when followSymlinks is
+			// true and its target resolves inside the workspace; stat
+			// through it to find out whether it points at a file or a
+			// directory so it can be recreated as one.
+			info, _, err := ws.Stat(srcRel)
+			if err != nil {
+				return err
+			}
+			isDir = info.IsDir()
+		}
+
+		if isDir {
+			if _, err := ws.Mkdir(destRel, 0755, true); err != nil {
+				return err
+			}
+			dirCount++
+			return nil
+		}
+
+		if !overwrite {
+			if _, _, err := ws.Lstat(destRel); err == nil {
+				return fmt.Errorf("%w: %s", fs.ErrExist, destRel)
+			} else if !errors.Is(err, fs.ErrNotExist) {
+				return err
+			}
+		}
+
+		written, _, _, err := copyFile(srcRel, destRel, true, preserveMode, followSymlinks)
+		if err != nil {
+			return err
+		}
+		fileCount++
+		bytesCopied += written
+		return nil
+	})
+	if err != nil {
+		if errors.Is(err, fs.ErrExist) {
+			return mcp.NewToolResultError(fmt.Sprintf("Destination already exists: %v", err)), nil
+		}
+		return mcp.NewToolResultError(fmt.Sprintf("Error copying directory: %v", err)), nil
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"directory_count": dirCount,
+		"file_count":      fileCount,
+		"bytes_copied":    bytesCopied,
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error encoding result: %v", err)), nil
+	}
+
+	log.Printf("Successfully copied directory: %s -> %s (%d files, %d dirs, %d bytes)", srcAbs, destAbs, fileCount, dirCount, bytesCopied)
+	return mcp.NewToolResultText(string(payload)), nil
+}
+
+// boolArg reads an optional boolean argument, returning def if the key is
+// absent.
+func boolArg(args map[string]interface{}, key string, def bool) (bool, error) {
+	raw, exists := args[key]
+	if !exists || raw == nil {
+		return def, nil
+	}
+	v, ok := raw.(bool)
+	if !ok {
+		return false, fmt.Errorf("parameter '%s' must be a boolean", key)
+	}
+	return v, nil
+}
@@ -0,0 +1,326 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// streamChunkSize bounds how much of a file read_file_range/write_file_range
+// buffer at once, so paging through a multi-GB file doesn't require holding
+// it all in memory at once.
+const streamChunkSize = 64 * 1024
+
+// encodeContent encodes raw bytes for an MCP response in the requested
+// wire encoding.
+func encodeContent(data []byte, encoding string) (string, error) {
+	switch encoding {
+	case "", "utf8":
+		return string(data), nil
+	case "base64":
+		return base64.StdEncoding.EncodeToString(data), nil
+	case "hex":
+		return hex.EncodeToString(data), nil
+	default:
+		return "", fmt.Errorf("unsupported encoding %q: must be utf8, base64, or hex", encoding)
+	}
+}
+
+// decodeContent reverses encodeContent for write_file_range.
+func decodeContent(content string, encoding string) ([]byte, error) {
+	switch encoding {
+	case "", "utf8":
+		return []byte(content), nil
+	case "base64":
+		return base64.StdEncoding.DecodeString(content)
+	case "hex":
+		return hex.DecodeString(content)
+	default:
+		return nil, fmt.Errorf("unsupported encoding %q: must be utf8, base64, or hex", encoding)
+	}
+}
+
+func readFileRangeHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+
+	filePathArg, exists := args["file_path"]
+	if !exists || filePathArg == nil {
+		return nil, fmt.Errorf("missing required parameter 'file_path'")
+	}
+	filePath, ok := filePathArg.(string)
+	if !ok {
+		return nil, fmt.Errorf("parameter 'file_path' must be a string")
+	}
+
+	offsetArg, exists := args["offset"]
+	if !exists || offsetArg == nil {
+		return nil, fmt.Errorf("missing required parameter 'offset'")
+	}
+	offset, ok := offsetArg.(float64)
+	if !ok {
+		return nil, fmt.Errorf("parameter 'offset' must be a number")
+	}
+
+	lengthArg, exists := args["length"]
+	if !exists || lengthArg == nil {
+		return nil, fmt.Errorf("missing required parameter 'length'")
+	}
+	length, ok := lengthArg.(float64)
+	if !ok {
+		return nil, fmt.Errorf("parameter 'length' must be a number")
+	}
+
+	encoding := "utf8"
+	if encodingArg, exists := args["encoding"]; exists && encodingArg != nil {
+		encoding, ok = encodingArg.(string)
+		if !ok {
+			return nil, fmt.Errorf("parameter 'encoding' must be a string")
+		}
+	}
+
+	file, cleanPath, err := ws.Open(filePath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return mcp.NewToolResultError(fmt.Sprintf("File not found: %s", filePath)), nil
+		}
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid file path: %v", err)), nil
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error reading file: %v", err)), nil
+	}
+
+	if _, err := file.Seek(int64(offset), io.SeekStart); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error seeking file: %v", err)), nil
+	}
+
+	data := make([]byte, 0, streamChunkSize)
+	buf := make([]byte, streamChunkSize)
+	remaining := int64(length)
+	for remaining > 0 {
+		want := int64(streamChunkSize)
+		if remaining < want {
+			want = remaining
+		}
+		n, err := file.Read(buf[:want])
+		if n > 0 {
+			data = append(data, buf[:n]...)
+			remaining -= int64(n)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Error reading file: %v", err)), nil
+		}
+		if n == 0 {
+			break
+		}
+	}
+
+	content, err := encodeContent(data, encoding)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	nextOffset := int64(offset) + int64(len(data))
+	payload, err := json.Marshal(map[string]interface{}{
+		"bytes_read":  len(data),
+		"next_offset": nextOffset,
+		"eof":         nextOffset >= info.Size(),
+		"encoding":    encoding,
+		"content":     content,
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error encoding result: %v", err)), nil
+	}
+
+	log.Printf("Successfully read range of file: %s (offset=%d, %d bytes)", cleanPath, int64(offset), len(data))
+	return mcp.NewToolResultText(string(payload)), nil
+}
+
+func writeFileRangeHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+
+	filePathArg, exists := args["file_path"]
+	if !exists || filePathArg == nil {
+		return nil, fmt.Errorf("missing required parameter 'file_path'")
+	}
+	filePath, ok := filePathArg.(string)
+	if !ok {
+		return nil, fmt.Errorf("parameter 'file_path' must be a string")
+	}
+
+	offsetArg, exists := args["offset"]
+	if !exists || offsetArg == nil {
+		return nil, fmt.Errorf("missing required parameter 'offset'")
+	}
+	offset, ok := offsetArg.(float64)
+	if !ok {
+		return nil, fmt.Errorf("parameter 'offset' must be a number")
+	}
+
+	contentArg, exists := args["content"]
+	if !exists || contentArg == nil {
+		return nil, fmt.Errorf("missing required parameter 'content'")
+	}
+	content, ok := contentArg.(string)
+	if !ok {
+		return nil, fmt.Errorf("parameter 'content' must be a string")
+	}
+
+	encoding := "utf8"
+	if encodingArg, exists := args["encoding"]; exists && encodingArg != nil {
+		encoding, ok = encodingArg.(string)
+		if !ok {
+			return nil, fmt.Errorf("parameter 'encoding' must be a string")
+		}
+	}
+
+	truncate := false
+	if truncateArg, exists := args["truncate"]; exists && truncateArg != nil {
+		truncate, ok = truncateArg.(bool)
+		if !ok {
+			return nil, fmt.Errorf("parameter 'truncate' must be a boolean")
+		}
+	}
+
+	data, err := decodeContent(content, encoding)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	if _, err := ws.Mkdir(filepath.Dir(filePath), 0755, true); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error creating directory: %v", err)), nil
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE
+	if truncate {
+		flags |= os.O_TRUNC
+	}
+	file, cleanPath, err := ws.Create(filePath, flags, 0644)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid file path: %v", err)), nil
+	}
+	defer file.Close()
+
+	if _, err := file.Seek(int64(offset), io.SeekStart); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error seeking file: %v", err)), nil
+	}
+
+	written := 0
+	for written < len(data) {
+		end := written + streamChunkSize
+		if end > len(data) {
+			end = len(data)
+		}
+		n, err := file.Write(data[written:end])
+		written += n
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Error writing file: %v", err)), nil
+		}
+	}
+
+	log.Printf("Successfully wrote range of file: %s (offset=%d, %d bytes)", cleanPath, int64(offset), written)
+	return mcp.NewToolResultText(fmt.Sprintf("Successfully wrote %d bytes to %s at offset %d", written, cleanPath, int64(offset))), nil
+}
+
+func appendFileHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+
+	filePathArg, exists := args["file_path"]
+	if !exists || filePathArg == nil {
+		return nil, fmt.Errorf("missing required parameter 'file_path'")
+	}
+	filePath, ok := filePathArg.(string)
+	if !ok {
+		return nil, fmt.Errorf("parameter 'file_path' must be a string")
+	}
+
+	contentArg, exists := args["content"]
+	if !exists || contentArg == nil {
+		return nil, fmt.Errorf("missing required parameter 'content'")
+	}
+	content, ok := contentArg.(string)
+	if !ok {
+		return nil, fmt.Errorf("parameter 'content' must be a string")
+	}
+
+	if _, err := ws.Mkdir(filepath.Dir(filePath), 0755, true); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error creating directory: %v", err)), nil
+	}
+
+	file, cleanPath, err := ws.Create(filePath, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid file path: %v", err)), nil
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(content); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error appending to file: %v", err)), nil
+	}
+
+	log.Printf("Successfully appended to file: %s (%d bytes)", cleanPath, len(content))
+	return mcp.NewToolResultText(fmt.Sprintf("Successfully appended %d bytes to %s", len(content), cleanPath)), nil
+}
+
+func statFileHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+
+	filePathArg, exists := args["file_path"]
+	if !exists || filePathArg == nil {
+		return nil, fmt.Errorf("missing required parameter 'file_path'")
+	}
+	filePath, ok := filePathArg.(string)
+	if !ok {
+		return nil, fmt.Errorf("parameter 'file_path' must be a string")
+	}
+
+	file, cleanPath, err := ws.Open(filePath)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return mcp.NewToolResultError(fmt.Sprintf("File not found: %s", filePath)), nil
+		}
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid file path: %v", err)), nil
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error reading file: %v", err)), nil
+	}
+	if info.IsDir() {
+		return mcp.NewToolResultError(fmt.Sprintf("Path is a directory, not a file: %s", cleanPath)), nil
+	}
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, file); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error hashing file: %v", err)), nil
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"size":   info.Size(),
+		"mtime":  info.ModTime().UTC().Format("2006-01-02T15:04:05Z07:00"),
+		"mode":   info.Mode().String(),
+		"sha256": hex.EncodeToString(hasher.Sum(nil)),
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error encoding result: %v", err)), nil
+	}
+
+	log.Printf("Successfully stat'd file: %s (%d bytes)", cleanPath, info.Size())
+	return mcp.NewToolResultText(string(payload)), nil
+}
@@ -0,0 +1,494 @@
+package main
+
+import (
+	"container/list"
+	"context"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"hash"
+	"io"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/bmatcuk/doublestar/v4"
+	"github.com/mark3labs/mcp-go/mcp"
+	"lukechampine.com/blake3"
+)
+
+const (
+	defaultGlobMaxResults   = 1000
+	defaultSearchMaxMatches = 10
+	globMetaChars           = "*?[]{}"
+)
+
+// hasGlobMeta reports whether path contains any doublestar/glob
+// metacharacter, used by hash_paths to decide whether to expand a path or
+// treat it as a literal file.
+func hasGlobMeta(path string) bool {
+	return strings.ContainsAny(path, globMetaChars)
+}
+
+// newHasher returns a fresh hash.Hash for the requested algorithm.
+func newHasher(algo string) (hash.Hash, error) {
+	switch algo {
+	case "", "sha256":
+		return sha256.New(), nil
+	case "sha1":
+		return sha1.New(), nil
+	case "blake3":
+		return blake3.New(32, nil), nil
+	default:
+		return nil, fmt.Errorf("unsupported algo %q: must be sha256, sha1, or blake3", algo)
+	}
+}
+
+func globFilesHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+
+	patternArg, exists := args["pattern"]
+	if !exists || patternArg == nil {
+		return nil, fmt.Errorf("missing required parameter 'pattern'")
+	}
+	pattern, ok := patternArg.(string)
+	if !ok {
+		return nil, fmt.Errorf("parameter 'pattern' must be a string")
+	}
+
+	rootPath := ""
+	if rootPathArg, exists := args["root_path"]; exists && rootPathArg != nil {
+		rootPath, ok = rootPathArg.(string)
+		if !ok {
+			return nil, fmt.Errorf("parameter 'root_path' must be a string")
+		}
+	}
+
+	followSymlinks := false
+	if followArg, exists := args["follow_symlinks"]; exists && followArg != nil {
+		followSymlinks, ok = followArg.(bool)
+		if !ok {
+			return nil, fmt.Errorf("parameter 'follow_symlinks' must be a boolean")
+		}
+	}
+
+	maxResults := defaultGlobMaxResults
+	if maxArg, exists := args["max_results"]; exists && maxArg != nil {
+		maxFloat, ok := maxArg.(float64)
+		if !ok {
+			return nil, fmt.Errorf("parameter 'max_results' must be a number")
+		}
+		maxResults = int(maxFloat)
+	}
+
+	var matches []string
+	truncated := false
+	err := ws.Walk(rootPath, followSymlinks, func(relPath string, d fs.DirEntry) error {
+		if len(matches) >= maxResults {
+			truncated = true
+			return fs.SkipAll
+		}
+		ok, err := doublestar.Match(pattern, relPath)
+		if err != nil {
+			return err
+		}
+		if ok {
+			matches = append(matches, relPath)
+		}
+		return nil
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error matching glob: %v", err)), nil
+	}
+	sort.Strings(matches)
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"matches":   matches,
+		"truncated": truncated,
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error encoding result: %v", err)), nil
+	}
+
+	log.Printf("Successfully matched glob %q under %s (%d matches)", pattern, filepath.Join(ws.Root(), rootPath), len(matches))
+	return mcp.NewToolResultText(string(payload)), nil
+}
+
+type searchHit struct {
+	File    string `json:"file"`
+	Line    int    `json:"line"`
+	Column  int    `json:"column"`
+	Preview string `json:"preview"`
+}
+
+func searchFilesHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+
+	regexArg, exists := args["regex"]
+	if !exists || regexArg == nil {
+		return nil, fmt.Errorf("missing required parameter 'regex'")
+	}
+	pattern, ok := regexArg.(string)
+	if !ok {
+		return nil, fmt.Errorf("parameter 'regex' must be a string")
+	}
+
+	pathGlob := "**"
+	if pathGlobArg, exists := args["path_glob"]; exists && pathGlobArg != nil {
+		pathGlob, ok = pathGlobArg.(string)
+		if !ok {
+			return nil, fmt.Errorf("parameter 'path_glob' must be a string")
+		}
+	}
+
+	maxMatchesPerFile := defaultSearchMaxMatches
+	if maxArg, exists := args["max_matches_per_file"]; exists && maxArg != nil {
+		maxFloat, ok := maxArg.(float64)
+		if !ok {
+			return nil, fmt.Errorf("parameter 'max_matches_per_file' must be a number")
+		}
+		maxMatchesPerFile = int(maxFloat)
+	}
+
+	contextLines := 0
+	if ctxArg, exists := args["context_lines"]; exists && ctxArg != nil {
+		ctxFloat, ok := ctxArg.(float64)
+		if !ok {
+			return nil, fmt.Errorf("parameter 'context_lines' must be a number")
+		}
+		contextLines = int(ctxFloat)
+	}
+
+	ignoreCase := false
+	if ignoreCaseArg, exists := args["ignore_case"]; exists && ignoreCaseArg != nil {
+		ignoreCase, ok = ignoreCaseArg.(bool)
+		if !ok {
+			return nil, fmt.Errorf("parameter 'ignore_case' must be a boolean")
+		}
+	}
+
+	if ignoreCase && !strings.HasPrefix(pattern, "(?i)") {
+		pattern = "(?i)" + pattern
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid regex: %v", err)), nil
+	}
+
+	var hits []searchHit
+	err = ws.Walk("", false, func(relPath string, d fs.DirEntry) error {
+		if d.IsDir() {
+			return nil
+		}
+		ok, err := doublestar.Match(pathGlob, relPath)
+		if err != nil || !ok {
+			return nil
+		}
+
+		fileHits, err := searchFile(relPath, re, maxMatchesPerFile, contextLines)
+		if err != nil {
+			return nil // unreadable (binary, permissions, etc.) - skip, don't abort the whole search
+		}
+		hits = append(hits, fileHits...)
+		return nil
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error searching files: %v", err)), nil
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"hits": hits,
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error encoding result: %v", err)), nil
+	}
+
+	log.Printf("Successfully searched for %q under %s (%d hits)", pattern, ws.Root(), len(hits))
+	return mcp.NewToolResultText(string(payload)), nil
+}
+
+// searchFile reads relPath through ws.Open rather than a path-based read, so
+// the file scanned is guaranteed to be the same one ws.Walk just reported,
+// with no window between the walk and the read for a path component to be
+// swapped out from under it.
+func searchFile(relPath string, re *regexp.Regexp, maxMatches, contextLines int) ([]searchHit, error) {
+	f, _, err := ws.Open(relPath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	content, err := io.ReadAll(f)
+	if err != nil {
+		return nil, err
+	}
+	if isBinary(content) {
+		return nil, nil
+	}
+	lines := strings.Split(string(content), "\n")
+
+	var hits []searchHit
+	for i, line := range lines {
+		if len(hits) >= maxMatches {
+			break
+		}
+		loc := re.FindStringIndex(line)
+		if loc == nil {
+			continue
+		}
+
+		start := i - contextLines
+		if start < 0 {
+			start = 0
+		}
+		end := i + contextLines
+		if end >= len(lines) {
+			end = len(lines) - 1
+		}
+
+		hits = append(hits, searchHit{
+			File:    relPath,
+			Line:    i + 1,
+			Column:  loc[0] + 1,
+			Preview: strings.Join(lines[start:end+1], "\n"),
+		})
+	}
+	return hits, nil
+}
+
+// isBinary applies the conventional heuristic of treating any NUL byte in
+// the first KiB as a sign the file isn't text, to keep the grep from
+// wasting time (and producing garbage matches) on binaries.
+func isBinary(content []byte) bool {
+	n := len(content)
+	if n > 1024 {
+		n = 1024
+	}
+	for _, b := range content[:n] {
+		if b == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// digestCacheKey identifies a cached per-file content digest. mtimeNs is
+// included (not just size) so an in-place edit that preserves file size
+// still invalidates the cache entry.
+type digestCacheKey struct {
+	absPath string
+	size    int64
+	mtimeNs int64
+	algo    string
+}
+
+// digestCache is a small LRU of (path, size, mtime, algo) -> content digest,
+// so repeated hash_paths calls over an unchanged tree don't re-read and
+// re-hash every file.
+type digestCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	entries  map[digestCacheKey]*list.Element
+}
+
+type digestCacheEntry struct {
+	key    digestCacheKey
+	digest []byte
+}
+
+var fileDigestCache = &digestCache{
+	capacity: 4096,
+	order:    list.New(),
+	entries:  make(map[digestCacheKey]*list.Element),
+}
+
+func (c *digestCache) get(key digestCacheKey) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+	c.order.MoveToFront(el)
+	return el.Value.(*digestCacheEntry).digest, true
+}
+
+func (c *digestCache) put(key digestCacheKey, digest []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if el, ok := c.entries[key]; ok {
+		el.Value.(*digestCacheEntry).digest = digest
+		c.order.MoveToFront(el)
+		return
+	}
+	el := c.order.PushFront(&digestCacheEntry{key: key, digest: digest})
+	c.entries[key] = el
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+		if oldest == nil {
+			break
+		}
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*digestCacheEntry).key)
+	}
+}
+
+// contentDigest returns H(content) for the file at relPath, using the LRU
+// cache when the file's size and mtime haven't changed since it was last
+// hashed with this algorithm.
+func contentDigest(relPath, algo string) ([]byte, error) {
+	file, absPath, err := ws.Open(relPath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	info, err := file.Stat()
+	if err != nil {
+		return nil, err
+	}
+
+	key := digestCacheKey{absPath: absPath, size: info.Size(), mtimeNs: info.ModTime().UnixNano(), algo: algo}
+	if digest, ok := fileDigestCache.get(key); ok {
+		return digest, nil
+	}
+
+	hasher, err := newHasher(algo)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := io.Copy(hasher, file); err != nil {
+		return nil, err
+	}
+	digest := hasher.Sum(nil)
+	fileDigestCache.put(key, digest)
+	return digest, nil
+}
+
+func hashPathsHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+
+	paths, err := stringArrayArg(args, "paths")
+	if err != nil {
+		return nil, err
+	}
+	if len(paths) == 0 {
+		return nil, fmt.Errorf("missing required parameter 'paths'")
+	}
+
+	algo := "sha256"
+	if algoArg, exists := args["algo"]; exists && algoArg != nil {
+		a, ok := algoArg.(string)
+		if !ok {
+			return nil, fmt.Errorf("parameter 'algo' must be a string")
+		}
+		algo = a
+	}
+	if _, err := newHasher(algo); err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	wildcard := true
+	if wildcardArg, exists := args["wildcard"]; exists && wildcardArg != nil {
+		w, ok := wildcardArg.(bool)
+		if !ok {
+			return nil, fmt.Errorf("parameter 'wildcard' must be a boolean")
+		}
+		wildcard = w
+	}
+
+	if ws.Root() == "" {
+		return mcp.NewToolResultError("LOCAL_WORKSPACE_FOLDER environment variable is not set"), nil
+	}
+
+	relSet := make(map[string]struct{})
+	for _, p := range paths {
+		if wildcard && hasGlobMeta(p) {
+			expanded, err := doublestar.Glob(os.DirFS(ws.Root()), p)
+			if err != nil {
+				return mcp.NewToolResultError(fmt.Sprintf("Invalid wildcard %q: %v", p, err)), nil
+			}
+			for _, m := range expanded {
+				relSet[m] = struct{}{}
+			}
+			continue
+		}
+
+		info, abs, err := ws.Stat(p)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Invalid path %q: %v", p, err)), nil
+		}
+		if info.IsDir() {
+			return mcp.NewToolResultError(fmt.Sprintf("Path is a directory, not a file: %s", abs)), nil
+		}
+		rel, err := filepath.Rel(ws.Root(), abs)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Error resolving path: %v", err)), nil
+		}
+		relSet[filepath.ToSlash(rel)] = struct{}{}
+	}
+
+	relPaths := make([]string, 0, len(relSet))
+	for rel := range relSet {
+		relPaths = append(relPaths, rel)
+	}
+	sort.Strings(relPaths)
+
+	type fileDigest struct {
+		Path   string `json:"path"`
+		Digest string `json:"digest"`
+	}
+	files := make([]fileDigest, 0, len(relPaths))
+	rawDigests := make([][]byte, 0, len(relPaths))
+
+	for _, rel := range relPaths {
+		contentH, err := contentDigest(rel, algo)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("Error hashing %s: %v", rel, err)), nil
+		}
+
+		hasher, err := newHasher(algo)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		hasher.Write([]byte(rel))
+		hasher.Write([]byte{0})
+		hasher.Write(contentH)
+		fileH := hasher.Sum(nil)
+
+		rawDigests = append(rawDigests, fileH)
+		files = append(files, fileDigest{Path: rel, Digest: hex.EncodeToString(fileH)})
+	}
+
+	sort.Slice(rawDigests, func(i, j int) bool {
+		return string(rawDigests[i]) < string(rawDigests[j])
+	})
+
+	topHasher, err := newHasher(algo)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	for _, d := range rawDigests {
+		topHasher.Write(d)
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{
+		"algo":   algo,
+		"digest": hex.EncodeToString(topHasher.Sum(nil)),
+		"files":  files,
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error encoding result: %v", err)), nil
+	}
+
+	log.Printf("Successfully hashed %d path(s) with %s", len(relPaths), algo)
+	return mcp.NewToolResultText(string(payload)), nil
+}
@@ -3,24 +3,48 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"io"
+	"io/fs"
 	"log"
 	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
+	"golang.org/x/sys/unix"
 )
 
+// mcpServer is set once in main and used by the watch subsystem to push
+// notifications/workspace_change notifications to a subscription's client
+// outside the request/response cycle that registered it.
+var mcpServer *server.MCPServer
+
 func main() {
 
+	// Resolve the workspace root once and pick an openat(2) mode before
+	// any tool call can race against it.
+	initWorkspace()
+
+	// Release any watch_path subscriptions a session held once it
+	// disconnects, so its fsnotify watchers and dispatch goroutines don't
+	// leak for the life of the process.
+	hooks := &server.Hooks{}
+	hooks.AddOnUnregisterSession(func(ctx context.Context, session server.ClientSession) {
+		unregisterWatchesForSession(session.SessionID())
+	})
+
 	// Create MCP server
 	s := server.NewMCPServer(
 		"mcp-files-server",
 		"0.0.0",
+		server.WithHooks(hooks),
 	)
+	mcpServer = s
 
 	// Read file tool
 	readFileTool := mcp.NewTool("read_file",
@@ -57,7 +81,7 @@ func main() {
 	// 		mcp.Description("Content to write to the file"),
 	// 	),
 	// )
-	// s.AddTool(generateFileTool, writeFileHandler)	
+	// s.AddTool(generateFileTool, writeFileHandler)
 
 	// Delete file tool
 	deleteFileTool := mcp.NewTool("delete_file",
@@ -99,6 +123,327 @@ func main() {
 	)
 	s.AddTool(listDirectoryTool, listDirectoryHandler)
 
+	// Read file range tool
+	readFileRangeTool := mcp.NewTool("read_file_range",
+		mcp.WithDescription("Read a byte range of a file, for paging through files too large for a single response"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to the file to read"),
+		),
+		mcp.WithNumber("offset",
+			mcp.Required(),
+			mcp.Description("Byte offset to start reading from"),
+		),
+		mcp.WithNumber("length",
+			mcp.Required(),
+			mcp.Description("Maximum number of bytes to read"),
+		),
+		mcp.WithString("encoding",
+			mcp.Description("Encoding for the returned content: utf8, base64, or hex (default: utf8)"),
+			mcp.Enum("utf8", "base64", "hex"),
+			mcp.DefaultString("utf8"),
+		),
+	)
+	s.AddTool(readFileRangeTool, readFileRangeHandler)
+
+	// Write file range tool
+	writeFileRangeTool := mcp.NewTool("write_file_range",
+		mcp.WithDescription("Write content to a file at a byte offset, without rewriting the whole file"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to the file to write"),
+		),
+		mcp.WithNumber("offset",
+			mcp.Required(),
+			mcp.Description("Byte offset to start writing at"),
+		),
+		mcp.WithString("content",
+			mcp.Required(),
+			mcp.Description("Content to write, in the given encoding"),
+		),
+		mcp.WithString("encoding",
+			mcp.Description("Encoding of 'content': utf8, base64, or hex (default: utf8)"),
+			mcp.Enum("utf8", "base64", "hex"),
+			mcp.DefaultString("utf8"),
+		),
+		mcp.WithBoolean("truncate",
+			mcp.Description("Truncate the file to the written content instead of leaving any trailing bytes in place (default: false)"),
+			mcp.DefaultBool(false),
+		),
+	)
+	s.AddTool(writeFileRangeTool, writeFileRangeHandler)
+
+	// Append file tool
+	appendFileTool := mcp.NewTool("append_file",
+		mcp.WithDescription("Append content to the end of a file, creating it if it doesn't exist"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to the file to append to"),
+		),
+		mcp.WithString("content",
+			mcp.Required(),
+			mcp.Description("Content to append to the file"),
+		),
+	)
+	s.AddTool(appendFileTool, appendFileHandler)
+
+	// Stat file tool
+	statFileTool := mcp.NewTool("stat_file",
+		mcp.WithDescription("Get a file's size, modification time, mode, and sha256 digest without reading its content"),
+		mcp.WithString("file_path",
+			mcp.Required(),
+			mcp.Description("Path to the file to stat"),
+		),
+	)
+	s.AddTool(statFileTool, statFileHandler)
+
+	// Archive directory tool
+	archiveDirectoryTool := mcp.NewTool("archive_directory",
+		mcp.WithDescription("Archive a directory into a zip, tar, or tar.gz file"),
+		mcp.WithString("directory_path",
+			mcp.Required(),
+			mcp.Description("Path to the directory to archive"),
+		),
+		mcp.WithString("output_path",
+			mcp.Required(),
+			mcp.Description("Path to write the archive to"),
+		),
+		mcp.WithString("format",
+			mcp.Description("Archive format: zip, tar, or tar.gz (default: zip)"),
+			mcp.Enum("zip", "tar", "tar.gz"),
+			mcp.DefaultString("zip"),
+		),
+		mcp.WithArray("exclude_globs",
+			mcp.Description("Glob patterns (matched against relative path and base name) to exclude from the archive"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+	)
+	s.AddTool(archiveDirectoryTool, archiveDirectoryHandler)
+
+	// Extract archive tool
+	extractArchiveTool := mcp.NewTool("extract_archive",
+		mcp.WithDescription("Extract a zip, tar, or tar.gz archive into a workspace directory"),
+		mcp.WithString("archive_path",
+			mcp.Required(),
+			mcp.Description("Path to the archive to extract"),
+		),
+		mcp.WithString("destination_path",
+			mcp.Required(),
+			mcp.Description("Directory to extract the archive into"),
+		),
+		mcp.WithString("format",
+			mcp.Description("Archive format: auto, zip, tar, or tar.gz (default: auto, detected from the file extension)"),
+			mcp.Enum("auto", "zip", "tar", "tar.gz"),
+			mcp.DefaultString("auto"),
+		),
+		mcp.WithNumber("strip_components",
+			mcp.Description("Number of leading path components to strip from each archive entry (default: 0)"),
+			mcp.DefaultNumber(0),
+		),
+	)
+	s.AddTool(extractArchiveTool, extractArchiveHandler)
+
+	// Glob files tool
+	globFilesTool := mcp.NewTool("glob_files",
+		mcp.WithDescription("Find files and directories under the workspace matching a doublestar glob pattern (supports ** for recursive matching)"),
+		mcp.WithString("pattern",
+			mcp.Required(),
+			mcp.Description("Glob pattern to match against paths relative to root_path, e.g. \"**/*.go\""),
+		),
+		mcp.WithString("root_path",
+			mcp.Description("Directory to match within, relative to the workspace root (default: workspace root)"),
+		),
+		mcp.WithBoolean("follow_symlinks",
+			mcp.Description("Follow symlinks that resolve within the workspace while walking (default: false)"),
+			mcp.DefaultBool(false),
+		),
+		mcp.WithNumber("max_results",
+			mcp.Description("Maximum number of matches to return (default: 1000)"),
+			mcp.DefaultNumber(defaultGlobMaxResults),
+		),
+	)
+	s.AddTool(globFilesTool, globFilesHandler)
+
+	// Search files tool
+	searchFilesTool := mcp.NewTool("search_files",
+		mcp.WithDescription("Search file contents under the workspace for a regular expression, grep-style, with optional surrounding context lines"),
+		mcp.WithString("regex",
+			mcp.Required(),
+			mcp.Description("Regular expression (RE2 syntax) to search for"),
+		),
+		mcp.WithString("path_glob",
+			mcp.Description("Glob pattern restricting which files are searched, relative to the workspace root (default: \"**\", all files)"),
+			mcp.DefaultString("**"),
+		),
+		mcp.WithNumber("max_matches_per_file",
+			mcp.Description("Maximum number of matches to report per file (default: 10)"),
+			mcp.DefaultNumber(defaultSearchMaxMatches),
+		),
+		mcp.WithNumber("context_lines",
+			mcp.Description("Number of lines of context to include before and after each match (default: 0)"),
+			mcp.DefaultNumber(0),
+		),
+		mcp.WithBoolean("ignore_case",
+			mcp.Description("Match case-insensitively (default: false)"),
+			mcp.DefaultBool(false),
+		),
+	)
+	s.AddTool(searchFilesTool, searchFilesHandler)
+
+	// Hash paths tool
+	hashPathsTool := mcp.NewTool("hash_paths",
+		mcp.WithDescription("Compute content digests for one or more workspace files and fold them into a single combined digest"),
+		mcp.WithArray("paths",
+			mcp.Required(),
+			mcp.Description("Paths to hash, relative to the workspace root. Entries containing glob metacharacters are expanded when wildcard is true"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithString("algo",
+			mcp.Description("Digest algorithm: sha256, sha1, or blake3 (default: sha256)"),
+			mcp.Enum("sha256", "sha1", "blake3"),
+			mcp.DefaultString("sha256"),
+		),
+		mcp.WithBoolean("wildcard",
+			mcp.Description("Expand entries in paths that contain glob metacharacters (default: true)"),
+			mcp.DefaultBool(true),
+		),
+	)
+	s.AddTool(hashPathsTool, hashPathsHandler)
+
+	// Copy file tool
+	copyFileTool := mcp.NewTool("copy_file",
+		mcp.WithDescription("Copy a single file to a new location within the workspace"),
+		mcp.WithString("source_path",
+			mcp.Required(),
+			mcp.Description("Path to the file to copy"),
+		),
+		mcp.WithString("destination_path",
+			mcp.Required(),
+			mcp.Description("Path to copy the file to"),
+		),
+		mcp.WithBoolean("overwrite",
+			mcp.Description("Overwrite destination_path if it already exists (default: false)"),
+			mcp.DefaultBool(false),
+		),
+		mcp.WithBoolean("preserve_mode",
+			mcp.Description("Give the copy the same permission bits as the source (default: true)"),
+			mcp.DefaultBool(true),
+		),
+		mcp.WithBoolean("follow_symlinks",
+			mcp.Description("Copy the content a symlinked source_path points to, instead of failing on it (default: false)"),
+			mcp.DefaultBool(false),
+		),
+	)
+	s.AddTool(copyFileTool, copyFileHandler)
+
+	// Move file tool
+	moveFileTool := mcp.NewTool("move_file",
+		mcp.WithDescription("Move a file to a new location within the workspace, falling back to copy+delete when source and destination are on different filesystems"),
+		mcp.WithString("source_path",
+			mcp.Required(),
+			mcp.Description("Path to the file to move"),
+		),
+		mcp.WithString("destination_path",
+			mcp.Required(),
+			mcp.Description("Path to move the file to"),
+		),
+		mcp.WithBoolean("overwrite",
+			mcp.Description("Overwrite destination_path if it already exists (default: false)"),
+			mcp.DefaultBool(false),
+		),
+	)
+	s.AddTool(moveFileTool, moveFileHandler)
+
+	// Rename tool
+	renameTool := mcp.NewTool("rename",
+		mcp.WithDescription("Rename or move a file or directory within the workspace using an atomic rename; fails if old_path and new_path are on different filesystems"),
+		mcp.WithString("old_path",
+			mcp.Required(),
+			mcp.Description("Path to rename"),
+		),
+		mcp.WithString("new_path",
+			mcp.Required(),
+			mcp.Description("New path"),
+		),
+	)
+	s.AddTool(renameTool, renameHandler)
+
+	// Copy directory tool
+	copyDirectoryTool := mcp.NewTool("copy_directory",
+		mcp.WithDescription("Recursively copy a directory tree to a new location within the workspace"),
+		mcp.WithString("source_path",
+			mcp.Required(),
+			mcp.Description("Path to the directory to copy"),
+		),
+		mcp.WithString("destination_path",
+			mcp.Required(),
+			mcp.Description("Path to copy the directory to"),
+		),
+		mcp.WithBoolean("overwrite",
+			mcp.Description("Overwrite files already present at the destination (default: false)"),
+			mcp.DefaultBool(false),
+		),
+		mcp.WithBoolean("preserve_mode",
+			mcp.Description("Give copied files the same permission bits as their source (default: true)"),
+			mcp.DefaultBool(true),
+		),
+		mcp.WithBoolean("follow_symlinks",
+			mcp.Description("Copy the content symlinks point to, instead of skipping them (default: false)"),
+			mcp.DefaultBool(false),
+		),
+		mcp.WithArray("exclude_globs",
+			mcp.Description("Glob patterns (matched against relative path and base name) to exclude from the copy"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+	)
+	s.AddTool(copyDirectoryTool, copyDirectoryHandler)
+
+	// Watch path tool
+	watchPathTool := mcp.NewTool("watch_path",
+		mcp.WithDescription("Subscribe to filesystem changes under a workspace path, delivered as notifications/workspace_change notifications"),
+		mcp.WithString("path",
+			mcp.Required(),
+			mcp.Description("File or directory to watch"),
+		),
+		mcp.WithBoolean("recursive",
+			mcp.Description("Watch subdirectories too, adding newly created ones and dropping removed ones as the tree changes (default: false)"),
+			mcp.DefaultBool(false),
+		),
+		mcp.WithArray("events",
+			mcp.Description("Event types to report: create, write, remove, rename, chmod (default: all of them)"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithNumber("debounce_ms",
+			mcp.Description("Coalesce events into one notification per this many milliseconds (default: 200)"),
+			mcp.DefaultNumber(defaultWatchDebounceMs),
+		),
+		mcp.WithArray("include_globs",
+			mcp.Description("If set, only report changes to paths (relative to path) matching one of these globs"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+		mcp.WithArray("exclude_globs",
+			mcp.Description("Glob patterns (matched against path-relative path and base name) to never report"),
+			mcp.Items(map[string]interface{}{"type": "string"}),
+		),
+	)
+	s.AddTool(watchPathTool, watchPathHandler)
+
+	// Unwatch tool
+	unwatchTool := mcp.NewTool("unwatch",
+		mcp.WithDescription("Cancel a subscription previously created with watch_path"),
+		mcp.WithString("watch_id",
+			mcp.Required(),
+			mcp.Description("ID returned by watch_path"),
+		),
+	)
+	s.AddTool(unwatchTool, unwatchHandler)
+
+	// List watches tool
+	listWatchesTool := mcp.NewTool("list_watches",
+		mcp.WithDescription("List active watch_path subscriptions"),
+	)
+	s.AddTool(listWatchesTool, listWatchesHandler)
+
 	// Tree view tool
 	treeViewTool := mcp.NewTool("tree_view",
 		mcp.WithDescription("Display a tree view of a directory structure"),
@@ -138,43 +483,6 @@ func main() {
 	log.Fatal(http.ListenAndServe(":"+httpPort, mux))
 }
 
-// validatePath ensures that the file path is within the workspace folder and prevents path traversal attacks
-func validatePath(userPath string) (string, error) {
-	workspaceFolder := os.Getenv("LOCAL_WORKSPACE_FOLDER")
-	if workspaceFolder == "" {
-		return "", fmt.Errorf("LOCAL_WORKSPACE_FOLDER environment variable is not set")
-	}
-
-	// Get absolute path of workspace folder
-	absWorkspace, err := filepath.Abs(workspaceFolder)
-	if err != nil {
-		return "", fmt.Errorf("error resolving workspace path: %v", err)
-	}
-
-	// Clean the user provided path
-	cleanUserPath := filepath.Clean(userPath)
-
-	// Remove any leading slashes to ensure it's treated as relative
-	cleanUserPath = strings.TrimPrefix(cleanUserPath, "/")
-	cleanUserPath = strings.TrimPrefix(cleanUserPath, "\\")
-
-	// Join with workspace folder
-	fullPath := filepath.Join(absWorkspace, cleanUserPath)
-
-	// Get absolute path to resolve any remaining .. or . components
-	absFullPath, err := filepath.Abs(fullPath)
-	if err != nil {
-		return "", fmt.Errorf("error resolving file path: %v", err)
-	}
-
-	// Check if the resolved path is still within the workspace
-	if !strings.HasPrefix(absFullPath, absWorkspace) {
-		return "", fmt.Errorf("access denied: path is outside workspace folder")
-	}
-
-	return absFullPath, nil
-}
-
 func createDirectoryHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
 	args := request.GetArguments()
 
@@ -188,18 +496,13 @@ func createDirectoryHandler(ctx context.Context, request mcp.CallToolRequest) (*
 		return nil, fmt.Errorf("parameter 'directory_path' must be a string")
 	}
 
-	// Validate and secure the directory path
-	cleanPath, err := validatePath(directoryPath)
+	// Create the directory and all parent directories, re-validating every
+	// component as it's created so no step can be swapped for a symlink.
+	cleanPath, err := ws.Mkdir(directoryPath, 0755, true)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Invalid directory path: %v", err)), nil
 	}
 
-	// Create the directory and all parent directories
-	err = os.MkdirAll(cleanPath, 0755)
-	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error creating directory: %v", err)), nil
-	}
-
 	log.Printf("Successfully created directory: %s", cleanPath)
 	return mcp.NewToolResultText(fmt.Sprintf("Successfully created directory: %s", cleanPath)), nil
 }
@@ -218,28 +521,19 @@ func deleteDirectoryHandler(ctx context.Context, request mcp.CallToolRequest) (*
 	}
 
 	// Validate and secure the directory path
-	cleanPath, err := validatePath(directoryPath)
+	info, cleanPath, err := ws.Stat(directoryPath)
 	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return mcp.NewToolResultError(fmt.Sprintf("Directory not found: %s", directoryPath)), nil
+		}
 		return mcp.NewToolResultError(fmt.Sprintf("Invalid directory path: %v", err)), nil
 	}
 
-	// Check if directory exists
-	info, err := os.Stat(cleanPath)
-	if os.IsNotExist(err) {
-		return mcp.NewToolResultError(fmt.Sprintf("Directory not found: %s", cleanPath)), nil
-	}
-	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error accessing directory: %v", err)), nil
-	}
-
-	// Check if it's actually a directory
 	if !info.IsDir() {
 		return mcp.NewToolResultError(fmt.Sprintf("Path is not a directory: %s", cleanPath)), nil
 	}
 
-	// Delete the directory and all its contents
-	err = os.RemoveAll(cleanPath)
-	if err != nil {
+	if _, err := ws.RemoveAll(directoryPath); err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Error deleting directory: %v", err)), nil
 	}
 
@@ -260,31 +554,22 @@ func listDirectoryHandler(ctx context.Context, request mcp.CallToolRequest) (*mc
 		return nil, fmt.Errorf("parameter 'directory_path' must be a string")
 	}
 
-	// Validate and secure the directory path
-	cleanPath, err := validatePath(directoryPath)
+	// Open the directory beneath the workspace root; a non-directory or an
+	// escaping path fails right here instead of at the later ReadDir call.
+	dir, cleanPath, err := ws.OpenDir(directoryPath)
 	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return mcp.NewToolResultError(fmt.Sprintf("Directory not found: %s", directoryPath)), nil
+		}
 		return mcp.NewToolResultError(fmt.Sprintf("Invalid directory path: %v", err)), nil
 	}
+	defer dir.Close()
 
-	// Check if directory exists
-	info, err := os.Stat(cleanPath)
-	if os.IsNotExist(err) {
-		return mcp.NewToolResultError(fmt.Sprintf("Directory not found: %s", cleanPath)), nil
-	}
-	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error accessing directory: %v", err)), nil
-	}
-
-	// Check if it's actually a directory
-	if !info.IsDir() {
-		return mcp.NewToolResultError(fmt.Sprintf("Path is not a directory: %s", cleanPath)), nil
-	}
-
-	// Read directory contents
-	entries, err := os.ReadDir(cleanPath)
+	entries, err := dir.ReadDir(-1)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Error reading directory: %v", err)), nil
 	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
 
 	// Build the result
 	var result strings.Builder
@@ -332,31 +617,20 @@ func treeViewHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.Cal
 		}
 	}
 
-	// Validate and secure the directory path
-	cleanPath, err := validatePath(directoryPath)
+	dir, cleanPath, err := ws.OpenDir(directoryPath)
 	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return mcp.NewToolResultError(fmt.Sprintf("Directory not found: %s", directoryPath)), nil
+		}
 		return mcp.NewToolResultError(fmt.Sprintf("Invalid directory path: %v", err)), nil
 	}
-
-	// Check if directory exists
-	info, err := os.Stat(cleanPath)
-	if os.IsNotExist(err) {
-		return mcp.NewToolResultError(fmt.Sprintf("Directory not found: %s", cleanPath)), nil
-	}
-	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Error accessing directory: %v", err)), nil
-	}
-
-	// Check if it's actually a directory
-	if !info.IsDir() {
-		return mcp.NewToolResultError(fmt.Sprintf("Path is not a directory: %s", cleanPath)), nil
-	}
+	defer dir.Close()
 
 	// Build tree view
 	var result strings.Builder
 	result.WriteString(fmt.Sprintf("Tree view of directory: %s\n\n", directoryPath))
 
-	err = buildTreeView(&result, cleanPath, "", 0, maxDepth)
+	err = buildTreeView(&result, dir, cleanPath, "", 0, maxDepth)
 	if err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Error building tree view: %v", err)), nil
 	}
@@ -365,15 +639,21 @@ func treeViewHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.Cal
 	return mcp.NewToolResultText(result.String()), nil
 }
 
-func buildTreeView(result *strings.Builder, path string, prefix string, currentDepth int, maxDepth int) error {
+// buildTreeView walks dir recursively, opening each subdirectory relative
+// to its already-open parent fd with O_NOFOLLOW so a symlink swapped in
+// after the parent listing can never be descended into. dirPath is dir's
+// real filesystem path; it's only used to name the *os.File we construct
+// for each subdirectory fd, since entry.Info() stats by name internally.
+func buildTreeView(result *strings.Builder, dir *os.File, dirPath string, prefix string, currentDepth int, maxDepth int) error {
 	if maxDepth >= 0 && currentDepth >= maxDepth {
 		return nil
 	}
 
-	entries, err := os.ReadDir(path)
+	entries, err := dir.ReadDir(-1)
 	if err != nil {
 		return err
 	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name() < entries[j].Name() })
 
 	for i, entry := range entries {
 		isLast := i == len(entries)-1
@@ -391,9 +671,16 @@ func buildTreeView(result *strings.Builder, path string, prefix string, currentD
 		// Write current entry
 		if entry.IsDir() {
 			result.WriteString(fmt.Sprintf("%s%s%s/\n", prefix, connector, entry.Name()))
-			// Recursively process subdirectory
-			subPath := filepath.Join(path, entry.Name())
-			err := buildTreeView(result, subPath, newPrefix, currentDepth+1, maxDepth)
+
+			subFd, err := unix.Openat(int(dir.Fd()), entry.Name(), unix.O_DIRECTORY|unix.O_RDONLY|unix.O_NOFOLLOW, 0)
+			if err != nil {
+				return err
+			}
+			subPath := filepath.Join(dirPath, entry.Name())
+			subDir := os.NewFile(uintptr(subFd), subPath)
+
+			err = buildTreeView(result, subDir, subPath, newPrefix, currentDepth+1, maxDepth)
+			subDir.Close()
 			if err != nil {
 				return err
 			}
@@ -423,18 +710,19 @@ func readFileHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.Cal
 		return nil, fmt.Errorf("parameter 'file_path' must be a string")
 	}
 
-	// Validate and secure the file path
-	cleanPath, err := validatePath(filePath)
+	// Resolve and open the file beneath the workspace root in one atomic
+	// step instead of validating a path and reading it separately.
+	file, cleanPath, err := ws.Open(filePath)
 	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return mcp.NewToolResultError(fmt.Sprintf("File not found: %s", filePath)), nil
+		}
 		return mcp.NewToolResultError(fmt.Sprintf("Invalid file path: %v", err)), nil
 	}
+	defer file.Close()
 
-	// Read the file
-	content, err := os.ReadFile(cleanPath)
+	content, err := io.ReadAll(file)
 	if err != nil {
-		if os.IsNotExist(err) {
-			return mcp.NewToolResultError(fmt.Sprintf("File not found: %s", cleanPath)), nil
-		}
 		return mcp.NewToolResultError(fmt.Sprintf("Error reading file: %v", err)), nil
 	}
 
@@ -465,21 +753,18 @@ func writeFileHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.Ca
 		return nil, fmt.Errorf("parameter 'content' must be a string")
 	}
 
-	// Validate and secure the file path
-	cleanPath, err := validatePath(filePath)
-	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Invalid file path: %v", err)), nil
-	}
-
-	// Create directory if it doesn't exist
-	dir := filepath.Dir(cleanPath)
-	if err := os.MkdirAll(dir, 0755); err != nil {
+	// Create the parent directory if it doesn't exist
+	if _, err := ws.Mkdir(filepath.Dir(filePath), 0755, true); err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Error creating directory: %v", err)), nil
 	}
 
-	// Write the file
-	err = os.WriteFile(cleanPath, []byte(content), 0644)
+	file, cleanPath, err := ws.Create(filePath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid file path: %v", err)), nil
+	}
+	defer file.Close()
+
+	if _, err := file.WriteString(content); err != nil {
 		return mcp.NewToolResultError(fmt.Sprintf("Error writing file: %v", err)), nil
 	}
 
@@ -500,20 +785,13 @@ func deleteFileHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.C
 		return nil, fmt.Errorf("parameter 'file_path' must be a string")
 	}
 
-	// Validate and secure the file path
-	cleanPath, err := validatePath(filePath)
-	if err != nil {
-		return mcp.NewToolResultError(fmt.Sprintf("Invalid file path: %v", err)), nil
-	}
-
-	// Check if file exists
-	if _, err := os.Stat(cleanPath); os.IsNotExist(err) {
-		return mcp.NewToolResultError(fmt.Sprintf("File not found: %s", cleanPath)), nil
-	}
-
-	// Delete the file
-	err = os.Remove(cleanPath)
+	// Unlink relative to the parent directory's fd, so the file removed is
+	// guaranteed to be the one that was validated.
+	cleanPath, err := ws.Remove(filePath)
 	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return mcp.NewToolResultError(fmt.Sprintf("File not found: %s", filePath)), nil
+		}
 		return mcp.NewToolResultError(fmt.Sprintf("Error deleting file: %v", err)), nil
 	}
 
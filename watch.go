@@ -0,0 +1,467 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/google/uuid"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/micro-agent/mcp-files-server/workspacefs"
+)
+
+// watchNotificationMethod is the server-initiated notification method
+// emitted for every debounced batch of changes under a watch_path
+// subscription.
+const watchNotificationMethod = "notifications/workspace_change"
+
+// defaultWatchDebounceMs is used when watch_path's debounce_ms is omitted.
+const defaultWatchDebounceMs = 200
+
+// defaultMaxWatches bounds how many concurrent watch_path subscriptions are
+// allowed when MCP_MAX_WATCHES isn't set, guarding against a client fanning
+// out unbounded fsnotify watchers.
+const defaultMaxWatches = 100
+
+var watchEventNames = map[string]fsnotify.Op{
+	"create": fsnotify.Create,
+	"write":  fsnotify.Write,
+	"remove": fsnotify.Remove,
+	"rename": fsnotify.Rename,
+	"chmod":  fsnotify.Chmod,
+}
+
+// watchEvent is one coalesced filesystem change reported in a
+// notifications/workspace_change payload. Path is always workspace-
+// relative and slash-separated, regardless of GOOS.
+type watchEvent struct {
+	Path  string `json:"path"`
+	Op    string `json:"op"`
+	Size  int64  `json:"size,omitempty"`
+	Mtime string `json:"mtime,omitempty"`
+}
+
+// watchSubscription tracks one watch_path registration: the fsnotify
+// watcher backing it, the directories it currently covers (which grows and
+// shrinks as recursive subdirectories are created/removed), and the
+// pending events waiting out their debounce window before being flushed as
+// a single notification.
+type watchSubscription struct {
+	id           string
+	rootRel      string
+	rootAbs      string
+	isDir        bool
+	singleFile   string // set when rootAbs names a file, not a directory
+	recursive    bool
+	eventNames   []string
+	eventOps     fsnotify.Op
+	includeGlobs []string
+	excludeGlobs []string
+	debounce     time.Duration
+	sessionID    string
+
+	watcher *fsnotify.Watcher
+
+	mu          sync.Mutex
+	watchedDirs map[string]struct{}
+	pending     map[string]watchEvent
+	timer       *time.Timer
+}
+
+var (
+	watchesMu sync.Mutex
+	watches   = make(map[string]*watchSubscription)
+)
+
+func maxWatches() int {
+	if v := os.Getenv("MCP_MAX_WATCHES"); v != "" {
+		if n, err := strconv.Atoi(v); err == nil && n > 0 {
+			return n
+		}
+	}
+	return defaultMaxWatches
+}
+
+// parseWatchEvents turns the events argument into the fsnotify ops a
+// subscription should report, defaulting to all of them when none are
+// given.
+func parseWatchEvents(names []string) (fsnotify.Op, error) {
+	if len(names) == 0 {
+		var all fsnotify.Op
+		for _, op := range watchEventNames {
+			all |= op
+		}
+		return all, nil
+	}
+
+	var ops fsnotify.Op
+	for _, name := range names {
+		op, ok := watchEventNames[strings.ToLower(name)]
+		if !ok {
+			return 0, fmt.Errorf("invalid event %q: must be one of create, write, remove, rename, chmod", name)
+		}
+		ops |= op
+	}
+	return ops, nil
+}
+
+// registerWatch opens an fsnotify watcher over relRoot (already resolved to
+// absRoot beneath the workspace root), adding every subdirectory too when
+// recursive is set, and starts the subscription's dispatch goroutine.
+func registerWatch(relRoot, absRoot string, isDir, recursive bool, eventNames, includeGlobs, excludeGlobs []string, debounce time.Duration, sessionID string) (string, error) {
+	allowedOps, err := parseWatchEvents(eventNames)
+	if err != nil {
+		return "", err
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return "", fmt.Errorf("error creating watcher: %w", err)
+	}
+
+	watchRoot := absRoot
+	singleFile := ""
+	if !isDir {
+		watchRoot = filepath.Dir(absRoot)
+		singleFile = filepath.Base(absRoot)
+	}
+
+	if err := watcher.Add(watchRoot); err != nil {
+		watcher.Close()
+		return "", fmt.Errorf("error watching %s: %w", relRoot, err)
+	}
+	watchedDirs := map[string]struct{}{watchRoot: {}}
+
+	if isDir && recursive {
+		err := ws.Walk(relRoot, false, func(rel string, d fs.DirEntry) error {
+			if !d.IsDir() {
+				return nil
+			}
+			abs := filepath.Join(absRoot, rel)
+			if err := watcher.Add(abs); err != nil {
+				return err
+			}
+			watchedDirs[abs] = struct{}{}
+			return nil
+		})
+		if err != nil {
+			watcher.Close()
+			return "", fmt.Errorf("error watching %s: %w", relRoot, err)
+		}
+	}
+
+	sub := &watchSubscription{
+		id:           uuid.NewString(),
+		rootRel:      relRoot,
+		rootAbs:      absRoot,
+		isDir:        isDir,
+		singleFile:   singleFile,
+		recursive:    recursive,
+		eventNames:   eventNames,
+		eventOps:     allowedOps,
+		includeGlobs: includeGlobs,
+		excludeGlobs: excludeGlobs,
+		debounce:     debounce,
+		sessionID:    sessionID,
+		watcher:      watcher,
+		watchedDirs:  watchedDirs,
+		pending:      make(map[string]watchEvent),
+	}
+
+	watchesMu.Lock()
+	if len(watches) >= maxWatches() {
+		watchesMu.Unlock()
+		watcher.Close()
+		return "", fmt.Errorf("maximum number of active watches (%d) reached", maxWatches())
+	}
+	watches[sub.id] = sub
+	watchesMu.Unlock()
+
+	go sub.run()
+	return sub.id, nil
+}
+
+// unregisterWatch removes and closes a subscription, reporting whether it
+// existed.
+func unregisterWatch(id string) bool {
+	watchesMu.Lock()
+	sub, ok := watches[id]
+	if ok {
+		delete(watches, id)
+	}
+	watchesMu.Unlock()
+	if !ok {
+		return false
+	}
+
+	sub.watcher.Close()
+	return true
+}
+
+// unregisterWatchesForSession closes and removes every subscription owned by
+// sessionID. It's wired up as an OnUnregisterSession hook so a disconnected
+// client's watchers and dispatch goroutines are released instead of leaking
+// for the life of the process.
+func unregisterWatchesForSession(sessionID string) {
+	watchesMu.Lock()
+	var owned []*watchSubscription
+	for id, sub := range watches {
+		if sub.sessionID == sessionID {
+			owned = append(owned, sub)
+			delete(watches, id)
+		}
+	}
+	watchesMu.Unlock()
+
+	for _, sub := range owned {
+		sub.watcher.Close()
+	}
+}
+
+// watchSummary is the list_watches view of one subscription.
+type watchSummary struct {
+	WatchID    string   `json:"watch_id"`
+	Path       string   `json:"path"`
+	Recursive  bool     `json:"recursive"`
+	Events     []string `json:"events"`
+	DebounceMs int64    `json:"debounce_ms"`
+}
+
+func listWatchSummaries() []watchSummary {
+	watchesMu.Lock()
+	defer watchesMu.Unlock()
+
+	summaries := make([]watchSummary, 0, len(watches))
+	for _, sub := range watches {
+		events := sub.eventNames
+		if len(events) == 0 {
+			events = []string{"create", "write", "remove", "rename", "chmod"}
+		}
+		summaries = append(summaries, watchSummary{
+			WatchID:    sub.id,
+			Path:       sub.rootRel,
+			Recursive:  sub.recursive,
+			Events:     events,
+			DebounceMs: sub.debounce.Milliseconds(),
+		})
+	}
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].WatchID < summaries[j].WatchID })
+	return summaries
+}
+
+// run dispatches fsnotify events to handleEvent until the watcher is
+// closed, either by unwatch or by unregisterWatchesForSession when the
+// owning session disconnects.
+func (sub *watchSubscription) run() {
+	defer sub.watcher.Close()
+	for {
+		select {
+		case event, ok := <-sub.watcher.Events:
+			if !ok {
+				return
+			}
+			sub.handleEvent(event)
+		case err, ok := <-sub.watcher.Errors:
+			if !ok {
+				return
+			}
+			log.Printf("watch %s: fsnotify error: %v", sub.id, err)
+		}
+	}
+}
+
+// handleEvent filters and queues a single fsnotify event, maintaining
+// recursive coverage as subdirectories are created or removed.
+func (sub *watchSubscription) handleEvent(event fsnotify.Event) {
+	if event.Op&sub.eventOps == 0 {
+		return
+	}
+
+	if sub.singleFile != "" && filepath.Base(event.Name) != sub.singleFile {
+		return
+	}
+
+	relPath, err := filepath.Rel(ws.Root(), event.Name)
+	if err != nil {
+		return
+	}
+	relPath = filepath.ToSlash(relPath)
+
+	matchRel := relPath
+	if sub.rootRel != "" {
+		if r, err := filepath.Rel(sub.rootRel, relPath); err == nil {
+			matchRel = filepath.ToSlash(r)
+		}
+	}
+	if len(sub.includeGlobs) > 0 && !matchesAnyGlob(sub.includeGlobs, matchRel) {
+		return
+	}
+	if matchesAnyGlob(sub.excludeGlobs, matchRel) {
+		return
+	}
+
+	if sub.recursive && event.Op&fsnotify.Create != 0 {
+		if info, err := os.Lstat(event.Name); err == nil && info.IsDir() && info.Mode()&os.ModeSymlink == 0 && workspacefs.WithinRoot(ws.Root(), event.Name) {
+			if err := sub.watcher.Add(event.Name); err == nil {
+				sub.mu.Lock()
+				sub.watchedDirs[event.Name] = struct{}{}
+				sub.mu.Unlock()
+			}
+		}
+	}
+	if event.Op&(fsnotify.Remove|fsnotify.Rename) != 0 {
+		sub.mu.Lock()
+		_, watched := sub.watchedDirs[event.Name]
+		if watched {
+			delete(sub.watchedDirs, event.Name)
+		}
+		sub.mu.Unlock()
+		if watched {
+			sub.watcher.Remove(event.Name)
+		}
+	}
+
+	we := watchEvent{Path: relPath, Op: strings.ToLower(event.Op.String())}
+	if info, err := os.Lstat(event.Name); err == nil {
+		we.Size = info.Size()
+		we.Mtime = info.ModTime().UTC().Format("2006-01-02T15:04:05Z07:00")
+	}
+
+	sub.mu.Lock()
+	sub.pending[relPath] = we
+	if sub.timer == nil {
+		sub.timer = time.AfterFunc(sub.debounce, sub.flush)
+	}
+	sub.mu.Unlock()
+}
+
+// flush sends every event queued since the last flush as one
+// notifications/workspace_change batch.
+func (sub *watchSubscription) flush() {
+	sub.mu.Lock()
+	events := make([]watchEvent, 0, len(sub.pending))
+	for _, e := range sub.pending {
+		events = append(events, e)
+	}
+	sub.pending = make(map[string]watchEvent)
+	sub.timer = nil
+	sub.mu.Unlock()
+
+	if len(events) == 0 || mcpServer == nil {
+		return
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Path < events[j].Path })
+
+	params := map[string]any{
+		"watch_id": sub.id,
+		"events":   events,
+	}
+	if err := mcpServer.SendNotificationToSpecificClient(sub.sessionID, watchNotificationMethod, params); err != nil {
+		log.Printf("watch %s: failed to send notification: %v", sub.id, err)
+	}
+}
+
+func watchPathHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+
+	pathArg, exists := args["path"]
+	if !exists || pathArg == nil {
+		return nil, fmt.Errorf("missing required parameter 'path'")
+	}
+	watchPath, ok := pathArg.(string)
+	if !ok {
+		return nil, fmt.Errorf("parameter 'path' must be a string")
+	}
+
+	recursive, err := boolArg(args, "recursive", false)
+	if err != nil {
+		return nil, err
+	}
+	eventNames, err := stringArrayArg(args, "events")
+	if err != nil {
+		return nil, err
+	}
+	includeGlobs, err := stringArrayArg(args, "include_globs")
+	if err != nil {
+		return nil, err
+	}
+	excludeGlobs, err := stringArrayArg(args, "exclude_globs")
+	if err != nil {
+		return nil, err
+	}
+
+	debounceMs := float64(defaultWatchDebounceMs)
+	if v, exists := args["debounce_ms"]; exists && v != nil {
+		f, ok := v.(float64)
+		if !ok {
+			return nil, fmt.Errorf("parameter 'debounce_ms' must be a number")
+		}
+		debounceMs = f
+	}
+
+	session := server.ClientSessionFromContext(ctx)
+	if session == nil {
+		return mcp.NewToolResultError("watch_path requires an active client session"), nil
+	}
+	sessionID := session.SessionID()
+
+	info, absPath, err := ws.Stat(watchPath)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Invalid path: %v", err)), nil
+	}
+
+	id, err := registerWatch(watchPath, absPath, info.IsDir(), recursive, eventNames, includeGlobs, excludeGlobs, time.Duration(debounceMs)*time.Millisecond, sessionID)
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error registering watch: %v", err)), nil
+	}
+
+	payload, err := json.Marshal(map[string]interface{}{"watch_id": id})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error encoding result: %v", err)), nil
+	}
+
+	log.Printf("Successfully registered watch %s on %s (recursive=%v)", id, absPath, recursive)
+	return mcp.NewToolResultText(string(payload)), nil
+}
+
+func unwatchHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	args := request.GetArguments()
+
+	watchIDArg, exists := args["watch_id"]
+	if !exists || watchIDArg == nil {
+		return nil, fmt.Errorf("missing required parameter 'watch_id'")
+	}
+	watchID, ok := watchIDArg.(string)
+	if !ok {
+		return nil, fmt.Errorf("parameter 'watch_id' must be a string")
+	}
+
+	if !unregisterWatch(watchID) {
+		return mcp.NewToolResultError(fmt.Sprintf("Watch not found: %s", watchID)), nil
+	}
+
+	log.Printf("Successfully removed watch %s", watchID)
+	return mcp.NewToolResultText(fmt.Sprintf("Successfully removed watch %s", watchID)), nil
+}
+
+func listWatchesHandler(ctx context.Context, request mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	summaries := listWatchSummaries()
+
+	payload, err := json.Marshal(map[string]interface{}{"watches": summaries})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("Error encoding result: %v", err)), nil
+	}
+
+	return mcp.NewToolResultText(string(payload)), nil
+}
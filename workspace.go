@@ -0,0 +1,36 @@
+package main
+
+import (
+	"log"
+	"os"
+
+	"github.com/micro-agent/mcp-files-server/workspacefs"
+)
+
+// ws is initialized once at startup by initWorkspace and used by every
+// handler. It always wraps a *workspacefs.LocalFS, even when
+// LOCAL_WORKSPACE_FOLDER was not set at startup, in which case it wraps a
+// nil one: every LocalFS method handles a nil receiver by returning the
+// same "not configured" error, so misconfigured deployments fail
+// per-request instead of crashing at boot or panicking on a nil interface.
+var ws workspacefs.FS = (*workspacefs.LocalFS)(nil)
+
+// initWorkspace opens the workspace root once, before any tool call can
+// race against it. It intentionally does not treat a missing
+// LOCAL_WORKSPACE_FOLDER as fatal, since some deployments probe the
+// process before the workspace is mounted; a real misconfiguration (the
+// variable is set but unusable) is fatal, since every tool call would
+// fail anyway.
+func initWorkspace() {
+	folder := os.Getenv("LOCAL_WORKSPACE_FOLDER")
+	if folder == "" {
+		log.Println("LOCAL_WORKSPACE_FOLDER is not set; file tools will error until it is configured")
+		return
+	}
+
+	fsys, err := workspacefs.NewLocal(folder)
+	if err != nil {
+		log.Fatalf("failed to initialize workspace: %v", err)
+	}
+	ws = fsys
+}
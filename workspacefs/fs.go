@@ -0,0 +1,70 @@
+// Package workspacefs abstracts the on-disk operations the MCP file tools
+// need behind a small FS interface, so the concrete backend -- today, a
+// workspace-root-confined local filesystem -- can be swapped out (for
+// tests, or for a future remote/object-storage backend) without touching
+// handler code.
+package workspacefs
+
+import (
+	"io/fs"
+	"os"
+)
+
+// WalkFunc is called for each entry visited by FS.Walk. relPath is slash-
+// separated and relative to the root path passed to Walk, regardless of
+// GOOS.
+type WalkFunc func(relPath string, d fs.DirEntry) error
+
+// FS resolves workspace-relative paths and performs filesystem operations
+// against them. Every method takes a path relative to the FS's root and
+// returns the resolved absolute path alongside its result, matching the
+// convention handlers already use for logging.
+//
+// Implementations must guarantee a path can never be resolved to somewhere
+// outside the root, including via a symlink swapped in between validation
+// and use.
+type FS interface {
+	// Root returns the absolute path the FS is confined to.
+	Root() string
+
+	// Open opens path read-only.
+	Open(path string) (f *os.File, absPath string, err error)
+	// OpenFollow is like Open but follows symlinks whose resolved target
+	// stays inside root, for callers (e.g. copy_file) that take a
+	// follow_symlinks option.
+	OpenFollow(path string) (f *os.File, absPath string, err error)
+	// OpenDir opens path as a readable directory.
+	OpenDir(path string) (f *os.File, absPath string, err error)
+	// Create opens path with the given flags (e.g. O_WRONLY|O_CREATE), creating
+	// it with perm if it doesn't already exist.
+	Create(path string, flags int, perm os.FileMode) (f *os.File, absPath string, err error)
+
+	Stat(path string) (fs.FileInfo, string, error)
+	// Lstat is like Stat but describes a path that is itself a symlink
+	// instead of following it.
+	Lstat(path string) (fs.FileInfo, string, error)
+
+	// Mkdir creates path, and its parents when recursive is true.
+	Mkdir(path string, perm os.FileMode, recursive bool) (absPath string, err error)
+	// Remove unlinks a single file.
+	Remove(path string) (absPath string, err error)
+	// RemoveAll removes path and, if it is a directory, its contents.
+	RemoveAll(path string) (absPath string, err error)
+	// Rename moves oldPath to newPath, creating newPath's parent directories
+	// as needed. Callers that care about crossing filesystem boundaries
+	// should be prepared to handle an EXDEV error and fall back to copying.
+	Rename(oldPath, newPath string) (oldAbs, newAbs string, err error)
+
+	// Walk walks the tree rooted at path, reporting entries relative to
+	// path. Symlinks are only descended into/reported when followSymlinks
+	// is true, and even then only when their target resolves inside root.
+	Walk(path string, followSymlinks bool, fn WalkFunc) error
+
+	// OpenArchive and ExtractArchive are thin, semantically named wrappers
+	// over Create/Open and Mkdir used by the archive tools, kept distinct
+	// so a backend that stages archive payloads differently (e.g. object
+	// storage) can override just these two without reimplementing the rest
+	// of FS.
+	OpenArchive(path string, flags int, perm os.FileMode) (f *os.File, absPath string, err error)
+	ExtractArchive(destPath string) (absPath string, err error)
+}
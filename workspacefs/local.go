@@ -0,0 +1,540 @@
+package workspacefs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/sys/unix"
+)
+
+// openatMode controls how LocalFS defends against symlink/TOCTOU escapes
+// when turning a user-supplied relative path into an open file descriptor.
+type openatMode string
+
+const (
+	openatModeAuto     openatMode = "auto"
+	openatModeOpenat2  openatMode = "openat2"
+	openatModePortable openatMode = "portable"
+)
+
+// LocalFS resolves user-supplied relative paths against a configured root
+// directory and hands back an already-open *os.File rather than a path
+// string, so a path can never be reinterpreted (e.g. via a symlink swapped
+// in after validation) between the check and its use.
+//
+// On Linux it prefers openat2(2) with RESOLVE_BENEATH, which the kernel
+// enforces atomically during path walk. Everywhere else, and whenever the
+// running kernel lacks openat2, it falls back to a portable component-by-
+// component Lstat walk that rejects any symlink crossing out of the root.
+type LocalFS struct {
+	root      *os.File
+	rootFd    int
+	rootPath  string
+	mode      openatMode
+	openat2OK bool
+}
+
+var _ FS = (*LocalFS)(nil)
+
+// NewLocal opens folder as a workspace root and picks an openat(2) mode.
+func NewLocal(folder string) (*LocalFS, error) {
+	absWorkspace, err := filepath.Abs(folder)
+	if err != nil {
+		return nil, fmt.Errorf("error resolving workspace path: %v", err)
+	}
+
+	mode := openatMode(os.Getenv("WORKSPACE_OPENAT_MODE"))
+	if mode == "" {
+		mode = openatModeAuto
+	}
+
+	rootFd, err := openWorkspaceRootFd(absWorkspace)
+	if err != nil {
+		return nil, fmt.Errorf("error opening workspace root %q: %v", absWorkspace, err)
+	}
+
+	w := &LocalFS{
+		root:     os.NewFile(uintptr(rootFd), absWorkspace),
+		rootFd:   rootFd,
+		rootPath: absWorkspace,
+		mode:     mode,
+	}
+
+	switch mode {
+	case openatModeOpenat2:
+		if !probeOpenat2() {
+			return nil, fmt.Errorf("WORKSPACE_OPENAT_MODE=openat2 requested but openat2(2) is not supported by this kernel")
+		}
+		w.openat2OK = true
+	case openatModePortable:
+		w.openat2OK = false
+	case openatModeAuto:
+		w.openat2OK = probeOpenat2()
+	default:
+		return nil, fmt.Errorf("invalid WORKSPACE_OPENAT_MODE %q: must be auto, openat2, or portable", mode)
+	}
+
+	log.Printf("workspace resolver ready: root=%s openat2=%v mode=%s", absWorkspace, w.openat2OK, mode)
+	return w, nil
+}
+
+// Root returns the absolute workspace root path, or "" if the FS wraps a
+// nil receiver (workspace not configured).
+func (w *LocalFS) Root() string {
+	if w == nil {
+		return ""
+	}
+	return w.rootPath
+}
+
+// sanitizeRelPath strips leading slashes so a user-supplied path is always
+// treated as relative to the workspace root.
+func sanitizeRelPath(userPath string) string {
+	clean := filepath.Clean(userPath)
+	clean = strings.TrimPrefix(clean, "/")
+	clean = strings.TrimPrefix(clean, "\\")
+	if clean == "." {
+		return ""
+	}
+	return clean
+}
+
+// splitParent splits rel into its parent (possibly "") and base name,
+// following the same convention Dir/Base use, so callers that need to
+// open a parent directory fd and operate on a single child don't repeat
+// the "." normalization everywhere.
+func splitParent(rel string) (parentRel, base string) {
+	parentRel = filepath.Dir(rel)
+	if parentRel == "." {
+		parentRel = ""
+	}
+	return parentRel, filepath.Base(rel)
+}
+
+// openRelative opens relPath beneath the workspace root with the given
+// flags/perm, preferring openat2(RESOLVE_BENEATH) and falling back to the
+// portable resolver. allowSymlinks switches to RESOLVE_IN_ROOT (openat2) or
+// permits in-root symlink targets (portable), for callers that intend to
+// follow symlinks that stay inside the workspace.
+func (w *LocalFS) openRelative(userPath string, flags int, perm os.FileMode, allowSymlinks bool) (f *os.File, absPath string, err error) {
+	if w == nil {
+		return nil, "", fmt.Errorf("LOCAL_WORKSPACE_FOLDER environment variable is not set")
+	}
+
+	rel := sanitizeRelPath(userPath)
+	absPath = filepath.Join(w.rootPath, rel)
+
+	if w.openat2OK {
+		fd, err := openBeneath(w.rootFd, rel, flags, uint32(perm.Perm()), allowSymlinks)
+		if err == nil {
+			return os.NewFile(uintptr(fd), absPath), absPath, nil
+		}
+		if !errors.Is(err, unix.ENOSYS) {
+			if errors.Is(err, unix.EXDEV) || errors.Is(err, unix.ELOOP) {
+				return nil, "", fmt.Errorf("access denied: %w", err)
+			}
+			return nil, "", err
+		}
+		// Kernel claimed openat2 support at boot but this call says
+		// otherwise (e.g. seccomp filtering it selectively); fall back.
+	}
+
+	safePath, err := resolvePortable(w.rootPath, rel, allowSymlinks)
+	if err != nil {
+		return nil, "", err
+	}
+	file, err := os.OpenFile(safePath, flags, perm)
+	if err != nil {
+		return nil, "", err
+	}
+	return file, safePath, nil
+}
+
+// Open opens path read-only beneath the workspace root.
+func (w *LocalFS) Open(userPath string) (*os.File, string, error) {
+	return w.openRelative(userPath, os.O_RDONLY, 0, false)
+}
+
+// OpenFollow is like Open but follows symlinks whose resolved target stays
+// inside the workspace root, for callers (e.g. copy_file) that take a
+// follow_symlinks option.
+func (w *LocalFS) OpenFollow(userPath string) (*os.File, string, error) {
+	return w.openRelative(userPath, os.O_RDONLY, 0, true)
+}
+
+// OpenDir opens path as a readable directory fd beneath the workspace root.
+// Unlike Open, the fd is opened O_RDONLY (not O_PATH) so handlers can call
+// ReadDir/Readdirnames on it directly.
+func (w *LocalFS) OpenDir(userPath string) (f *os.File, absPath string, err error) {
+	return w.openRelative(userPath, unix.O_DIRECTORY|unix.O_RDONLY, 0, false)
+}
+
+// Create opens path beneath the workspace root with the given flags/perm.
+func (w *LocalFS) Create(userPath string, flags int, perm os.FileMode) (*os.File, string, error) {
+	return w.openRelative(userPath, flags, perm, false)
+}
+
+// Stat resolves path beneath the workspace root and returns its FileInfo
+// without ever handing a path string to a path-based stat call.
+func (w *LocalFS) Stat(userPath string) (fs.FileInfo, string, error) {
+	f, absPath, err := w.openRelative(userPath, unix.O_PATH, 0, false)
+	if err != nil {
+		return nil, "", err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return nil, "", err
+	}
+	return info, absPath, nil
+}
+
+// Lstat resolves path's parent directory beneath the workspace root (so no
+// symlink in a parent component can redirect it), then lstats the final
+// component in place without following it, describing the symlink itself
+// if path is one.
+func (w *LocalFS) Lstat(userPath string) (fs.FileInfo, string, error) {
+	if w == nil {
+		return nil, "", fmt.Errorf("LOCAL_WORKSPACE_FOLDER environment variable is not set")
+	}
+
+	rel := sanitizeRelPath(userPath)
+	if rel == "" {
+		return w.Stat(userPath)
+	}
+
+	parentRel, base := splitParent(rel)
+	parent, parentAbs, err := w.OpenDir(parentRel)
+	if err != nil {
+		return nil, "", err
+	}
+	defer parent.Close()
+
+	absPath := filepath.Join(parentAbs, base)
+	info, err := os.Lstat(absPath)
+	if err != nil {
+		return nil, "", fmt.Errorf("access denied: %w", err)
+	}
+	return info, absPath, nil
+}
+
+// Mkdir creates path beneath the workspace root, re-validating every
+// component as it's created so no step can be swapped for a symlink. When
+// recursive is true, missing parents are created too (mkdir -p semantics);
+// otherwise path's parent must already exist.
+func (w *LocalFS) Mkdir(userPath string, perm os.FileMode, recursive bool) (absPath string, err error) {
+	if w == nil {
+		return "", fmt.Errorf("LOCAL_WORKSPACE_FOLDER environment variable is not set")
+	}
+
+	rel := sanitizeRelPath(userPath)
+	absPath = filepath.Join(w.rootPath, rel)
+
+	if recursive {
+		if w.openat2OK {
+			if err := mkdirAllBeneath(w.rootFd, rel, perm); err != nil {
+				return "", fmt.Errorf("access denied: %w", err)
+			}
+			return absPath, nil
+		}
+
+		safePath, err := resolvePortableAllowMissing(w.rootPath, rel)
+		if err != nil {
+			return "", err
+		}
+		if err := os.MkdirAll(safePath, perm); err != nil {
+			return "", err
+		}
+		return safePath, nil
+	}
+
+	parentRel, base := splitParent(rel)
+	parent, parentAbs, err := w.OpenDir(parentRel)
+	if err != nil {
+		return "", err
+	}
+	defer parent.Close()
+
+	if err := unix.Mkdirat(int(parent.Fd()), base, uint32(perm.Perm())); err != nil {
+		return "", fmt.Errorf("access denied: %w", err)
+	}
+	return filepath.Join(parentAbs, base), nil
+}
+
+// Remove unlinks a single file beneath the workspace root by opening its
+// parent directory and calling Unlinkat relative to that fd, so the file
+// that gets removed is guaranteed to be the same one that was validated.
+func (w *LocalFS) Remove(userPath string) (absPath string, err error) {
+	if w == nil {
+		return "", fmt.Errorf("LOCAL_WORKSPACE_FOLDER environment variable is not set")
+	}
+
+	rel := sanitizeRelPath(userPath)
+	absPath = filepath.Join(w.rootPath, rel)
+
+	parentRel, base := splitParent(rel)
+	parent, _, err := w.OpenDir(parentRel)
+	if err != nil {
+		return "", err
+	}
+	defer parent.Close()
+
+	if err := unix.Unlinkat(int(parent.Fd()), base, 0); err != nil {
+		return "", fmt.Errorf("access denied: %w", err)
+	}
+	return absPath, nil
+}
+
+// RemoveAll removes path, and its contents if it's a directory.
+func (w *LocalFS) RemoveAll(userPath string) (absPath string, err error) {
+	info, cleanPath, err := w.Stat(userPath)
+	if err != nil {
+		return "", err
+	}
+	if !info.IsDir() {
+		return w.Remove(userPath)
+	}
+
+	// os.RemoveAll has no fd-relative equivalent for an entire subtree, but
+	// cleanPath was just confirmed to resolve beneath the workspace root
+	// with no symlink components, so this reuses that validated path.
+	if err := os.RemoveAll(cleanPath); err != nil {
+		return "", err
+	}
+	return cleanPath, nil
+}
+
+// Rename moves oldPath to newPath using Renameat relative to each path's
+// already-open parent directory fd, creating newPath's parent directories
+// first if needed.
+func (w *LocalFS) Rename(oldUserPath, newUserPath string) (oldAbs, newAbs string, err error) {
+	if w == nil {
+		return "", "", fmt.Errorf("LOCAL_WORKSPACE_FOLDER environment variable is not set")
+	}
+
+	oldRel := sanitizeRelPath(oldUserPath)
+	newRel := sanitizeRelPath(newUserPath)
+
+	oldParentRel, oldBase := splitParent(oldRel)
+	oldParent, oldParentAbs, err := w.OpenDir(oldParentRel)
+	if err != nil {
+		return "", "", err
+	}
+	defer oldParent.Close()
+
+	newParentRel, newBase := splitParent(newRel)
+	if _, err := w.Mkdir(newParentRel, 0755, true); err != nil {
+		return "", "", err
+	}
+	newParent, newParentAbs, err := w.OpenDir(newParentRel)
+	if err != nil {
+		return "", "", err
+	}
+	defer newParent.Close()
+
+	if err := unix.Renameat(int(oldParent.Fd()), oldBase, int(newParent.Fd()), newBase); err != nil {
+		return "", "", fmt.Errorf("access denied: %w", err)
+	}
+	return filepath.Join(oldParentAbs, oldBase), filepath.Join(newParentAbs, newBase), nil
+}
+
+// Walk walks the tree rooted at path, reporting each entry's slash-
+// separated path relative to path. Symlinks are only descended into/
+// reported when followSymlinks is true, and even then only when their
+// target resolves inside the workspace root.
+func (w *LocalFS) Walk(userPath string, followSymlinks bool, fn WalkFunc) error {
+	info, rootAbs, err := w.Stat(userPath)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("not a directory: %s", rootAbs)
+	}
+
+	return filepath.WalkDir(rootAbs, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if p == rootAbs {
+			return nil
+		}
+
+		if d.Type()&os.ModeSymlink != 0 {
+			if !followSymlinks {
+				if d.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+			target, err := os.Readlink(p)
+			if err != nil {
+				return nil
+			}
+			resolved := target
+			if !filepath.IsAbs(resolved) {
+				resolved = filepath.Join(filepath.Dir(p), target)
+			}
+			if !WithinRoot(w.rootPath, filepath.Clean(resolved)) {
+				if d.IsDir() {
+					return fs.SkipDir
+				}
+				return nil
+			}
+		}
+
+		relPath, err := filepath.Rel(rootAbs, p)
+		if err != nil {
+			return err
+		}
+		return fn(filepath.ToSlash(relPath), d)
+	})
+}
+
+// OpenArchive opens path beneath the workspace root for reading an
+// existing archive or writing a new one, depending on flags.
+func (w *LocalFS) OpenArchive(userPath string, flags int, perm os.FileMode) (*os.File, string, error) {
+	return w.openRelative(userPath, flags, perm, false)
+}
+
+// ExtractArchive ensures destPath (and its parents) exist beneath the
+// workspace root, returning its absolute path for archive entries to be
+// written under.
+func (w *LocalFS) ExtractArchive(destPath string) (string, error) {
+	return w.Mkdir(destPath, 0755, true)
+}
+
+// resolvePortable walks relPath component by component using Lstat,
+// rejecting any symlink (unless allowSymlinks is set) or resolved target
+// that would escape root. It is the fallback used on non-Linux platforms
+// and whenever openat2 support is unavailable; the final component is
+// allowed to not exist yet, since callers like write_file create it.
+func resolvePortable(root string, relPath string, allowSymlinks bool) (string, error) {
+	parts := splitRelPath(relPath)
+	current := root
+
+	for i, part := range parts {
+		next := filepath.Join(current, part)
+
+		info, err := os.Lstat(next)
+		if err != nil {
+			if os.IsNotExist(err) && i == len(parts)-1 {
+				current = next
+				break
+			}
+			return "", fmt.Errorf("access denied: %w", err)
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			if !allowSymlinks {
+				return "", fmt.Errorf("access denied: refusing to resolve symlink in path: %s", part)
+			}
+			next, err = resolveSymlinkChain(root, next)
+			if err != nil {
+				return "", err
+			}
+		}
+
+		if !WithinRoot(root, next) {
+			return "", fmt.Errorf("access denied: path is outside workspace folder")
+		}
+		current = next
+	}
+
+	if !WithinRoot(root, current) {
+		return "", fmt.Errorf("access denied: path is outside workspace folder")
+	}
+	return current, nil
+}
+
+// resolveSymlinkChain follows the symlink at path to its final non-symlink
+// target, re-validating WithinRoot at every hop. A single Readlink only
+// resolves one hop: an in-root symlink pointing at a second symlink that
+// itself escapes root would otherwise pass the first check and then have
+// its remaining hops followed unchecked by the eventual os.OpenFile call.
+// maxSymlinkHops bounds how many hops it'll follow, so a symlink cycle
+// fails instead of looping forever.
+const maxSymlinkHops = 40
+
+func resolveSymlinkChain(root, path string) (string, error) {
+	for hops := 0; ; hops++ {
+		if hops >= maxSymlinkHops {
+			return "", fmt.Errorf("access denied: too many levels of symbolic links: %s", path)
+		}
+
+		info, err := os.Lstat(path)
+		if err != nil {
+			return "", fmt.Errorf("access denied: %w", err)
+		}
+		if info.Mode()&os.ModeSymlink == 0 {
+			return path, nil
+		}
+
+		target, err := os.Readlink(path)
+		if err != nil {
+			return "", err
+		}
+		if filepath.IsAbs(target) {
+			return "", fmt.Errorf("access denied: refusing to resolve absolute symlink target: %s", target)
+		}
+		path = filepath.Clean(filepath.Join(filepath.Dir(path), target))
+		if !WithinRoot(root, path) {
+			return "", fmt.Errorf("access denied: path is outside workspace folder")
+		}
+	}
+}
+
+// resolvePortableAllowMissing is like resolvePortable but allows any
+// trailing run of missing components, not just the last one, since
+// Mkdir(recursive=true) needs to create several levels at once.
+func resolvePortableAllowMissing(root string, relPath string) (string, error) {
+	parts := splitRelPath(relPath)
+	current := root
+
+	for _, part := range parts {
+		next := filepath.Join(current, part)
+
+		info, err := os.Lstat(next)
+		if err != nil {
+			if os.IsNotExist(err) {
+				current = next
+				continue
+			}
+			return "", fmt.Errorf("access denied: %w", err)
+		}
+
+		if info.Mode()&os.ModeSymlink != 0 {
+			return "", fmt.Errorf("access denied: refusing to resolve symlink in path: %s", part)
+		}
+
+		if !WithinRoot(root, next) {
+			return "", fmt.Errorf("access denied: path is outside workspace folder")
+		}
+		current = next
+	}
+
+	if !WithinRoot(root, current) {
+		return "", fmt.Errorf("access denied: path is outside workspace folder")
+	}
+	return current, nil
+}
+
+func splitRelPath(relPath string) []string {
+	clean := sanitizeRelPath(relPath)
+	if clean == "" {
+		return nil
+	}
+	return strings.Split(clean, string(filepath.Separator))
+}
+
+func WithinRoot(root, candidate string) bool {
+	if candidate == root {
+		return true
+	}
+	return strings.HasPrefix(candidate, root+string(filepath.Separator))
+}
@@ -0,0 +1,29 @@
+//go:build !linux
+
+package workspacefs
+
+import (
+	"fmt"
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// On non-Linux platforms there is no openat2(2), so LocalFS always falls
+// back to the portable Lstat-walk resolver in local.go.
+
+func openWorkspaceRootFd(path string) (int, error) {
+	return unix.Open(path, unix.O_DIRECTORY, 0)
+}
+
+func probeOpenat2() bool {
+	return false
+}
+
+func openBeneath(rootFd int, relPath string, flags int, mode uint32, allowSymlinks bool) (int, error) {
+	return -1, fmt.Errorf("openat2 is not supported on this platform")
+}
+
+func mkdirAllBeneath(rootFd int, relPath string, perm os.FileMode) error {
+	return fmt.Errorf("openat2 is not supported on this platform")
+}
@@ -0,0 +1,89 @@
+//go:build linux
+
+package workspacefs
+
+import (
+	"os"
+
+	"golang.org/x/sys/unix"
+)
+
+// openWorkspaceRootFd opens the workspace root once at startup with
+// O_PATH|O_DIRECTORY, giving every subsequent openat2 call a stable
+// dirfd to resolve beneath, independent of the root ever being renamed.
+func openWorkspaceRootFd(path string) (int, error) {
+	return unix.Open(path, unix.O_PATH|unix.O_DIRECTORY, 0)
+}
+
+// probeOpenat2 detects kernel support for openat2(2) (Linux 5.6+) by
+// issuing a harmless call against "/" and checking whether it's rejected
+// with ENOSYS.
+func probeOpenat2() bool {
+	fd, err := unix.Openat2(unix.AT_FDCWD, "/", &unix.OpenHow{Flags: unix.O_PATH})
+	if err != nil {
+		return false
+	}
+	unix.Close(fd)
+	return true
+}
+
+// openBeneath opens relPath relative to rootFd using openat2(2). With
+// allowSymlinks false it uses RESOLVE_BENEATH|RESOLVE_NO_MAGICLINKS|
+// RESOLVE_NO_SYMLINKS, so any symlink component anywhere in the path
+// fails the open atomically instead of being silently followed. With
+// allowSymlinks true it uses RESOLVE_IN_ROOT, which follows symlinks but
+// keeps their resolved targets pinned beneath rootFd.
+func openBeneath(rootFd int, relPath string, flags int, mode uint32, allowSymlinks bool) (int, error) {
+	// openat2 has no AT_EMPTY_PATH fallback: an empty relPath (the
+	// workspace root itself) would resolve to ENOENT instead of rootFd, so
+	// every operation on "." (list_directory/glob_files/search_files with
+	// no root_path, archive_directory/copy_directory of the root, ...)
+	// would fail on an openat2-capable kernel. "." resolves to the same
+	// directory without that special case.
+	if relPath == "" {
+		relPath = "."
+	}
+
+	resolve := uint64(unix.RESOLVE_BENEATH | unix.RESOLVE_NO_MAGICLINKS | unix.RESOLVE_NO_SYMLINKS)
+	if allowSymlinks {
+		resolve = unix.RESOLVE_IN_ROOT
+	}
+
+	how := unix.OpenHow{
+		Flags:   uint64(flags),
+		Mode:    uint64(mode),
+		Resolve: resolve,
+	}
+	return unix.Openat2(rootFd, relPath, &how)
+}
+
+// mkdirAllBeneath creates relPath (and any missing parents) one component
+// at a time, each time reopening the just-created/verified directory with
+// RESOLVE_BENEATH before creating the next component inside it, so the
+// whole chain is guaranteed to stay under rootFd even if a component is
+// swapped for a symlink mid-walk.
+func mkdirAllBeneath(rootFd int, relPath string, perm os.FileMode) error {
+	parts := splitRelPath(relPath)
+	if len(parts) == 0 {
+		return nil
+	}
+
+	dirFd := rootFd
+	closeDirFd := func() {}
+	defer func() { closeDirFd() }()
+
+	for _, part := range parts {
+		if err := unix.Mkdirat(dirFd, part, uint32(perm.Perm())); err != nil && err != unix.EEXIST {
+			return err
+		}
+
+		nextFd, err := openBeneath(dirFd, part, unix.O_DIRECTORY|unix.O_PATH, 0, false)
+		if err != nil {
+			return err
+		}
+		closeDirFd()
+		dirFd = nextFd
+		closeDirFd = func() { unix.Close(nextFd) }
+	}
+	return nil
+}
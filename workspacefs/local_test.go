@@ -0,0 +1,319 @@
+package workspacefs
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// newTestFS builds a LocalFS rooted at a fresh temp directory in the given
+// openat mode, skipping the test if mode is explicitly "openat2" and the
+// kernel doesn't support it.
+func newTestFS(t *testing.T, mode string) *LocalFS {
+	t.Helper()
+
+	if mode == string(openatModeOpenat2) && !probeOpenat2() {
+		t.Skip("openat2(2) not supported by this kernel")
+	}
+
+	root := t.TempDir()
+	t.Setenv("WORKSPACE_OPENAT_MODE", mode)
+
+	w, err := NewLocal(root)
+	if err != nil {
+		t.Fatalf("NewLocal(%q) error: %v", root, err)
+	}
+	return w
+}
+
+// forEachMode runs fn once per resolver mode this platform can exercise, so
+// escape checks are verified against both the openat2 fast path and the
+// portable fallback instead of whichever one the test host happens to pick.
+func forEachMode(t *testing.T, fn func(t *testing.T, w *LocalFS)) {
+	t.Helper()
+	for _, mode := range []string{string(openatModePortable), string(openatModeOpenat2)} {
+		mode := mode
+		t.Run(mode, func(t *testing.T) {
+			w := newTestFS(t, mode)
+			fn(t, w)
+		})
+	}
+}
+
+func TestLocalFS_Open_RejectsPathEscapes(t *testing.T) {
+	forEachMode(t, func(t *testing.T, w *LocalFS) {
+		if err := os.WriteFile(filepath.Join(w.Root(), "secret.txt"), []byte("outside"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		outsideDir := filepath.Dir(w.Root())
+		if err := os.WriteFile(filepath.Join(outsideDir, "secret.txt"), []byte("outside"), 0644); err == nil {
+			defer os.Remove(filepath.Join(outsideDir, "secret.txt"))
+		}
+
+		cases := []string{
+			"../secret.txt",
+			"../../secret.txt",
+			"a/../../secret.txt",
+			"a/../../../secret.txt",
+		}
+		for _, p := range cases {
+			if _, _, err := w.Open(p); err == nil {
+				t.Errorf("Open(%q): expected error, got nil", p)
+			}
+		}
+	})
+}
+
+func TestLocalFS_Open_TreatsAbsolutePathsAsWorkspaceRelative(t *testing.T) {
+	forEachMode(t, func(t *testing.T, w *LocalFS) {
+		// An absolute-looking path must never reach the real filesystem
+		// root; it should be reinterpreted as relative to the workspace.
+		if err := os.MkdirAll(filepath.Join(w.Root(), "etc"), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(w.Root(), "etc", "passwd"), []byte("workspace copy"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		f, absPath, err := w.Open("/etc/passwd")
+		if err != nil {
+			t.Fatalf("Open(\"/etc/passwd\") error: %v", err)
+		}
+		defer f.Close()
+
+		if !WithinRoot(w.Root(), absPath) {
+			t.Fatalf("resolved path %q escaped workspace root %q", absPath, w.Root())
+		}
+		data, err := os.ReadFile(absPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != "workspace copy" {
+			t.Fatalf("read unexpected content %q; real /etc/passwd may have leaked", data)
+		}
+	})
+}
+
+func TestLocalFS_Open_RejectsSymlinkEscape(t *testing.T) {
+	forEachMode(t, func(t *testing.T, w *LocalFS) {
+		outsideDir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(outsideDir, "payload.txt"), []byte("outside"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		link := filepath.Join(w.Root(), "escape")
+		if err := os.Symlink(filepath.Join(outsideDir, "payload.txt"), link); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, _, err := w.Open("escape"); err == nil {
+			t.Error("Open of a symlink should fail even though it's the last path component")
+		}
+		if _, _, err := w.OpenFollow("escape"); err == nil {
+			t.Error("OpenFollow should refuse to follow a symlink whose target escapes the workspace")
+		}
+	})
+}
+
+func TestLocalFS_OpenFollow_AllowsInRootSymlink(t *testing.T) {
+	forEachMode(t, func(t *testing.T, w *LocalFS) {
+		if err := os.WriteFile(filepath.Join(w.Root(), "real.txt"), []byte("hi"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		// A relative symlink target is what the portable resolver is
+		// willing to follow in-root; an absolute target is always
+		// rejected, even when it happens to point inside the workspace.
+		link := filepath.Join(w.Root(), "link.txt")
+		if err := os.Symlink("real.txt", link); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, _, err := w.Open("link.txt"); err == nil {
+			t.Error("Open (no follow) should refuse a symlink even if its target is in-root")
+		}
+
+		f, _, err := w.OpenFollow("link.txt")
+		if err != nil {
+			t.Fatalf("OpenFollow(in-root symlink) error: %v", err)
+		}
+		defer f.Close()
+	})
+}
+
+func TestLocalFS_OpenFollow_RejectsChainedSymlinkEscape(t *testing.T) {
+	forEachMode(t, func(t *testing.T, w *LocalFS) {
+		outsideDir := t.TempDir()
+		if err := os.WriteFile(filepath.Join(outsideDir, "secret.txt"), []byte("outside"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		// link2 is a symlink that itself escapes the workspace; link1 is an
+		// in-root symlink pointing at link2. Resolving only one hop (link1
+		// -> link2) looks safe since link2's path is in-root, but the
+		// second hop (link2 -> outside) must still be rejected.
+		outsideTarget, err := filepath.Rel(w.Root(), filepath.Join(outsideDir, "secret.txt"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		link2 := filepath.Join(w.Root(), "link2")
+		if err := os.Symlink(outsideTarget, link2); err != nil {
+			t.Fatal(err)
+		}
+		link1 := filepath.Join(w.Root(), "link1")
+		if err := os.Symlink("link2", link1); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, _, err := w.OpenFollow("link1"); err == nil {
+			t.Error("OpenFollow should refuse a symlink chain whose second hop escapes the workspace")
+		}
+	})
+}
+
+func TestLocalFS_Open_AllowsLegitimateNestedPath(t *testing.T) {
+	forEachMode(t, func(t *testing.T, w *LocalFS) {
+		nested := filepath.Join(w.Root(), "a", "b", "c")
+		if err := os.MkdirAll(nested, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(nested, "file.txt"), []byte("ok"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		f, absPath, err := w.Open("a/b/c/file.txt")
+		if err != nil {
+			t.Fatalf("Open(legitimate nested path) error: %v", err)
+		}
+		defer f.Close()
+		if !WithinRoot(w.Root(), absPath) {
+			t.Fatalf("resolved path %q is not within root %q", absPath, w.Root())
+		}
+	})
+}
+
+func TestLocalFS_Mkdir_RejectsEscapeAndAllowsNested(t *testing.T) {
+	forEachMode(t, func(t *testing.T, w *LocalFS) {
+		if _, err := w.Mkdir("../escape-dir", 0755, true); err == nil {
+			t.Error("Mkdir(\"../escape-dir\", recursive) should fail")
+		}
+
+		absPath, err := w.Mkdir("x/y/z", 0755, true)
+		if err != nil {
+			t.Fatalf("Mkdir(nested, recursive) error: %v", err)
+		}
+		info, err := os.Stat(absPath)
+		if err != nil || !info.IsDir() {
+			t.Fatalf("expected %q to be a created directory", absPath)
+		}
+		if !WithinRoot(w.Root(), absPath) {
+			t.Fatalf("Mkdir escaped workspace root: %q", absPath)
+		}
+	})
+}
+
+func TestLocalFS_Rename_RejectsEscapeAndAllowsInRootMove(t *testing.T) {
+	forEachMode(t, func(t *testing.T, w *LocalFS) {
+		if err := os.WriteFile(filepath.Join(w.Root(), "src.txt"), []byte("move me"), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, _, err := w.Rename("src.txt", "../escaped.txt"); err == nil {
+			t.Error("Rename to a path outside the workspace should fail")
+		}
+		if _, err := os.Stat(filepath.Join(w.Root(), "src.txt")); err != nil {
+			t.Fatalf("source should be untouched after a rejected rename: %v", err)
+		}
+
+		oldAbs, newAbs, err := w.Rename("src.txt", "dir/dst.txt")
+		if err != nil {
+			t.Fatalf("Rename(in-root) error: %v", err)
+		}
+		if !WithinRoot(w.Root(), oldAbs) || !WithinRoot(w.Root(), newAbs) {
+			t.Fatalf("Rename resolved paths outside the workspace: %q -> %q", oldAbs, newAbs)
+		}
+		if _, err := os.Stat(newAbs); err != nil {
+			t.Fatalf("expected renamed file to exist at %q: %v", newAbs, err)
+		}
+	})
+}
+
+func TestResolvePortable_RejectsEscapesAndSymlinks(t *testing.T) {
+	root := t.TempDir()
+
+	// A relative symlink target is resolved against its containing
+	// directory, exactly like the kernel would; these two exercise that
+	// resolution landing inside vs. outside root without needing the
+	// symlink's target to actually exist on disk.
+	if err := os.Symlink("../escaped-dir", filepath.Join(root, "out-link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Mkdir(filepath.Join(root, "inroot"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("inroot", filepath.Join(root, "in-link")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Join(root, "a", "b"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		name          string
+		rel           string
+		allowSymlinks bool
+		wantErr       bool
+	}{
+		{"dot-dot escape", "../escape", false, true},
+		{"nested dot-dot escape", "a/../../escape", false, true},
+		{"symlink component rejected by default", "out-link/payload.txt", false, true},
+		{"symlink component rejected even in-root", "in-link/file.txt", false, true},
+		{"symlink component followed when allowed, in-root", "in-link/file.txt", true, false},
+		{"symlink component followed when allowed, escapes", "out-link/payload.txt", true, true},
+		{"plain nested path", "a/b/c", false, false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := resolvePortable(root, tc.rel, tc.allowSymlinks)
+			if tc.wantErr && err == nil {
+				t.Errorf("resolvePortable(%q, allowSymlinks=%v): expected error, got nil", tc.rel, tc.allowSymlinks)
+			}
+			if !tc.wantErr && err != nil {
+				t.Errorf("resolvePortable(%q, allowSymlinks=%v): unexpected error: %v", tc.rel, tc.allowSymlinks, err)
+			}
+		})
+	}
+}
+
+func TestResolvePortableAllowMissing_RejectsEscapesAndSymlinks(t *testing.T) {
+	root := t.TempDir()
+	outsideDir := t.TempDir()
+	if err := os.Symlink(outsideDir, filepath.Join(root, "out-link")); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := resolvePortableAllowMissing(root, "../a/b"); err == nil {
+		t.Error("expected error for a relative path that escapes the root")
+	}
+	if _, err := resolvePortableAllowMissing(root, "out-link/a/b"); err == nil {
+		t.Error("expected error when a symlink component is present")
+	}
+
+	abs, err := resolvePortableAllowMissing(root, "a/b/c")
+	if err != nil {
+		t.Fatalf("resolvePortableAllowMissing(missing nested path) error: %v", err)
+	}
+	if !WithinRoot(root, abs) {
+		t.Fatalf("resolved path %q escaped root %q", abs, root)
+	}
+}
+
+func TestLocalFS_Stat_NotExist(t *testing.T) {
+	forEachMode(t, func(t *testing.T, w *LocalFS) {
+		if _, _, err := w.Stat("does/not/exist"); !errors.Is(err, fs.ErrNotExist) {
+			t.Fatalf("Stat(missing path): expected fs.ErrNotExist, got %v", err)
+		}
+	})
+}